@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+)
+
+const (
+	APIKeyHeader    = "X-Api-Key"
+	KeyIDHeader     = "X-Api-Key-Id"
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+
+	ReadScope     = "read"
+	RetryScope    = "retry"
+	RollbackScope = "rollback"
+	exportScope   = "export:"
+
+	defaultMaxSkew = 5 * time.Minute
+	//maxSignedBodyBytes bounds the body of a signed request, read whole to check its signature
+	maxSignedBodyBytes = 1 << 20
+)
+
+var errBodyTooLarge = fmt.Errorf("request body exceeds %d bytes", maxSignedBodyBytes)
+
+//ExportScope is the scope allowing to export the given concept type
+func ExportScope(conceptType string) string {
+	return exportScope + conceptType
+}
+
+//Key is a client of the API, authenticated either by sending its secret or by signing its requests with it.
+//Its scopes are read, retry, rollback and export:<concept type>, export:* allowing to export every concept type
+type Key struct {
+	ID     string   `json:"ID"`
+	Secret string   `json:"Secret"`
+	Scopes []string `json:"Scopes"`
+}
+
+//Can tells whether the key has the scope
+func (k Key) Can(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || (strings.HasSuffix(s, ":*") && strings.HasPrefix(scope, strings.TrimSuffix(s, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+//ParseKeys reads the keys from a JSON array
+func ParseKeys(keys string) ([]Key, error) {
+	var result []Key
+	if keys == "" {
+		return result, nil
+	}
+	if err := json.Unmarshal([]byte(keys), &result); err != nil {
+		return nil, fmt.Errorf("invalid API keys: %v", err)
+	}
+	for _, k := range result {
+		if k.ID == "" || k.Secret == "" {
+			return nil, fmt.Errorf("invalid API keys: every key needs an ID and a secret")
+		}
+	}
+	return result, nil
+}
+
+type contextKey struct{}
+
+//FromContext returns the key the request was authenticated with
+func FromContext(ctx context.Context) (Key, bool) {
+	k, ok := ctx.Value(contextKey{}).(Key)
+	return k, ok
+}
+
+//Authenticator authenticates the requests with the static API key of the APIKeyHeader, or with the HMAC-SHA256 signature of the
+//export.SignatureHeader, computed with the secret of the key of the KeyIDHeader over the method, the request URI, the TimestampHeader, the NonceHeader
+//and the body, separated by new lines. Signed requests are rejected when their timestamp is more than MaxSkew away or their nonce was already seen,
+//the nonces being kept until their timestamp expired
+type Authenticator struct {
+	sync.Mutex
+	keys    []Key
+	nonces  map[string]time.Time
+	MaxSkew time.Duration
+}
+
+func NewAuthenticator(keys []Key) *Authenticator {
+	return &Authenticator{keys: keys, nonces: map[string]time.Time{}, MaxSkew: defaultMaxSkew}
+}
+
+//Middleware rejects unauthenticated requests and the reads of keys without the read scope. Other scopes are checked by the handlers
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		key, err := a.authenticate(writer, request)
+		if err == errBodyTooLarge {
+			problem.Write(writer, http.StatusRequestEntityTooLarge, err.Error(), nil)
+			return
+		}
+		if err != nil {
+			writer.Header().Set("WWW-Authenticate", `ApiKey header="`+APIKeyHeader+`"`)
+			problem.Write(writer, http.StatusUnauthorized, err.Error(), nil)
+			return
+		}
+		if (request.Method == http.MethodGet || request.Method == http.MethodHead) && !key.Can(ReadScope) {
+			problem.Write(writer, http.StatusForbidden, fmt.Sprintf("API key %v is not allowed to read jobs", key.ID), nil)
+			return
+		}
+		next.ServeHTTP(writer, request.WithContext(context.WithValue(request.Context(), contextKey{}, key)))
+	})
+}
+
+func (a *Authenticator) authenticate(writer http.ResponseWriter, request *http.Request) (Key, error) {
+	if secret := request.Header.Get(APIKeyHeader); secret != "" {
+		for _, k := range a.keys {
+			if subtle.ConstantTimeCompare([]byte(secret), []byte(k.Secret)) == 1 {
+				return k, nil
+			}
+		}
+		return Key{}, fmt.Errorf("invalid API key")
+	}
+	id := request.Header.Get(KeyIDHeader)
+	if id == "" {
+		return Key{}, fmt.Errorf("missing API key")
+	}
+	key, found := a.key(id)
+	if !found {
+		return Key{}, fmt.Errorf("invalid API key")
+	}
+	timestamp, err := strconv.ParseInt(request.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid %v header", TimestampHeader)
+	}
+	if skew := time.Since(time.Unix(timestamp, 0)); skew > a.MaxSkew || skew < -a.MaxSkew {
+		return Key{}, fmt.Errorf("expired request signature")
+	}
+	nonce := request.Header.Get(NonceHeader)
+	if nonce == "" {
+		return Key{}, fmt.Errorf("missing %v header", NonceHeader)
+	}
+	var body []byte
+	if request.Body != nil {
+		body, err = ioutil.ReadAll(http.MaxBytesReader(writer, request.Body, maxSignedBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return Key{}, errBodyTooLarge
+		}
+		if err != nil {
+			return Key{}, err
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	signature := export.Sign(SigningPayload(request.Method, request.URL.RequestURI(), timestamp, nonce, body), key.Secret)
+	if !hmac.Equal([]byte(signature), []byte(request.Header.Get(export.SignatureHeader))) {
+		return Key{}, fmt.Errorf("invalid request signature")
+	}
+	if !a.useNonce(key.ID, nonce, time.Unix(timestamp, 0).Add(a.MaxSkew)) {
+		return Key{}, fmt.Errorf("replayed request signature")
+	}
+	return key, nil
+}
+
+//useNonce records the nonce of a key until it expires, telling whether it wasn't already recorded. Expired nonces are forgotten
+func (a *Authenticator) useNonce(keyID, nonce string, expires time.Time) bool {
+	a.Lock()
+	defer a.Unlock()
+	now := time.Now()
+	for n, e := range a.nonces {
+		if !now.Before(e) {
+			delete(a.nonces, n)
+		}
+	}
+	id := keyID + "\n" + nonce
+	if _, seen := a.nonces[id]; seen {
+		return false
+	}
+	a.nonces[id] = expires
+	return true
+}
+
+func (a *Authenticator) key(id string) (Key, bool) {
+	for _, k := range a.keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+//SigningPayload is what the clients sign their requests over, the request URI being the path with the query
+func SigningPayload(method, requestURI string, timestamp int64, nonce string, body []byte) []byte {
+	return []byte(method + "\n" + requestURI + "\n" + strconv.FormatInt(timestamp, 10) + "\n" + nonce + "\n" + string(body))
+}
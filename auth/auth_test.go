@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey_Can(t *testing.T) {
+	key := Key{ID: "ops", Scopes: []string{ReadScope, ExportScope("Brand"), "retry:*"}}
+
+	assert.True(t, key.Can(ReadScope))
+	assert.True(t, key.Can(ExportScope("Brand")))
+	assert.False(t, key.Can(ExportScope("Person")))
+	assert.False(t, key.Can(RollbackScope))
+	assert.True(t, Key{Scopes: []string{"export:*"}}.Can(ExportScope("Person")))
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(`[{"ID":"ops","Secret":"secret","Scopes":["read","export:*"]}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Key{{ID: "ops", Secret: "secret", Scopes: []string{"read", "export:*"}}}, keys)
+
+	keys, err = ParseKeys("")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = ParseKeys(`[{"ID":"ops"}]`)
+	assert.EqualError(t, err, "invalid API keys: every key needs an ID and a secret")
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	authenticator := NewAuthenticator([]Key{
+		{ID: "ops", Secret: "ops-secret", Scopes: []string{ReadScope, "export:*"}},
+		{ID: "writer", Secret: "writer-secret", Scopes: []string{ExportScope("Brand")}},
+	})
+	handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := FromContext(r.Context())
+		assert.True(t, ok)
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		_, _ = w.Write([]byte(key.ID + ":" + string(body)))
+	}))
+	signedURI := func(method, uri, signedURI, id, secret string, timestamp int64, nonce, body string) *http.Request {
+		req := httptest.NewRequest(method, uri, strings.NewReader(body))
+		req.Header.Set(KeyIDHeader, id)
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(NonceHeader, nonce)
+		req.Header.Set(export.SignatureHeader, export.Sign(SigningPayload(method, signedURI, timestamp, nonce, []byte(body)), secret))
+		return req
+	}
+	signed := func(method, id, secret string, timestamp int64, nonce, body string) *http.Request {
+		return signedURI(method, "/export", "/export", id, secret, timestamp, nonce, body)
+	}
+	withKey := func(method, secret string) *http.Request {
+		req := httptest.NewRequest(method, "/export", strings.NewReader(`{}`))
+		req.Header.Set(APIKeyHeader, secret)
+		return req
+	}
+	now := time.Now().Unix()
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+		body    string
+	}{
+		{name: "API key", request: withKey(http.MethodGet, "ops-secret"), status: http.StatusOK, body: "ops:{}"},
+		{name: "Invalid API key", request: withKey(http.MethodPost, "guess"), status: http.StatusUnauthorized, body: "invalid API key"},
+		{name: "Missing API key", request: httptest.NewRequest(http.MethodPost, "/export", nil), status: http.StatusUnauthorized, body: "missing API key"},
+		{name: "Read without read scope", request: withKey(http.MethodGet, "writer-secret"), status: http.StatusForbidden, body: "API key writer is not allowed to read jobs"},
+		{name: "Signed request", request: signed(http.MethodPost, "writer", "writer-secret", now, "nonce-1", `{"conceptTypes":"Brand"}`), status: http.StatusOK, body: `writer:{"conceptTypes":"Brand"}`},
+		{name: "Replayed signature", request: signed(http.MethodPost, "writer", "writer-secret", now, "nonce-1", `{"conceptTypes":"Brand"}`), status: http.StatusUnauthorized, body: "replayed request signature"},
+		{name: "Signed query", request: signedURI(http.MethodPost, "/export?dryRun=true", "/export?dryRun=true", "writer", "writer-secret", now, "nonce-2", `{}`), status: http.StatusOK, body: "writer:{}"},
+		{name: "Unsigned query", request: signedURI(http.MethodPost, "/export?dryRun=true", "/export", "writer", "writer-secret", now, "nonce-3", `{}`), status: http.StatusUnauthorized, body: "invalid request signature"},
+		{name: "Missing nonce", request: signed(http.MethodPost, "writer", "writer-secret", now, "", `{}`), status: http.StatusUnauthorized, body: "missing X-Nonce header"},
+		{name: "Body too large", request: signed(http.MethodPost, "writer", "writer-secret", now, "nonce-4", strings.Repeat(" ", maxSignedBodyBytes+1)), status: http.StatusRequestEntityTooLarge, body: "request body exceeds 1048576 bytes"},
+		{name: "Wrong signature", request: signed(http.MethodPost, "writer", "ops-secret", now, "nonce-5", `{}`), status: http.StatusUnauthorized, body: "invalid request signature"},
+		{name: "Unknown key ID", request: signed(http.MethodPost, "reader", "ops-secret", now, "nonce-6", `{}`), status: http.StatusUnauthorized, body: "invalid API key"},
+		{name: "Expired signature", request: signed(http.MethodPost, "writer", "writer-secret", now-600, "nonce-7", `{}`), status: http.StatusUnauthorized, body: "expired request signature"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, test.request)
+			assert.Equal(t, test.status, recorder.Code)
+			if test.status == http.StatusOK {
+				assert.Equal(t, test.body, recorder.Body.String())
+				return
+			}
+			assert.Equal(t, problem.ContentType, recorder.Header().Get("Content-Type"))
+			var p problem.Problem
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &p))
+			assert.Equal(t, test.status, p.Status)
+			assert.Equal(t, test.body, p.Detail)
+		})
+	}
+}
+
+func TestAuthenticator_UseNonceForgetsExpiredNonces(t *testing.T) {
+	authenticator := NewAuthenticator(nil)
+
+	assert.True(t, authenticator.useNonce("ops", "nonce-1", time.Now().Add(-time.Second)))
+	assert.True(t, authenticator.useNonce("ops", "nonce-2", time.Now().Add(time.Minute)))
+	assert.True(t, authenticator.useNonce("writer", "nonce-2", time.Now().Add(time.Minute)))
+	assert.False(t, authenticator.useNonce("ops", "nonce-2", time.Now().Add(time.Minute)))
+	assert.Len(t, authenticator.nonces, 2)
+}
@@ -1,30 +1,172 @@
 package concept
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/concept-exporter/monitoring"
+	"github.com/Financial-Times/concept-exporter/tracing"
 	"github.com/Financial-Times/go-logger/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type State string
 
 const (
+	QUEUED   State = "Queued"
 	STARTING State = "Starting"
 	RUNNING  State = "Running"
 	FINISHED State = "Finished"
 )
 
+const (
+	//Annotations is the export type of the annotations between content and the exported concepts
+	Annotations = "Annotations"
+	//Concordance is the export type of the source identifiers of the exported concepts
+	Concordance = "Concordance"
+)
+
+//Kind tells what a job exports. An empty kind is a concept export
+type Kind string
+
+const (
+	CONCEPTS      Kind = "Concepts"
+	COOCCURRENCES Kind = "CoOccurrences"
+	TRENDING      Kind = "Trending"
+)
+
+const defaultPageSize = 10000
+
+//DefaultTrendingDays is the window of a trending export requesting no days
+const DefaultTrendingDays = 7
+
+//Options are the export request parameters deciding what is inquired for a job
+type Options struct {
+	db.ReadOptions
+	Kind        Kind `json:"Kind,omitempty"`
+	Annotations bool `json:"Annotations,omitempty"`
+	Concordance bool `json:"Concordance,omitempty"`
+	MinCount    int  `json:"MinCount,omitempty"`
+	Days        int  `json:"Days,omitempty"`
+	Compare     bool `json:"Compare,omitempty"`
+	//Types restricts the export to the given export types, e.g. to the failed ones of an earlier job
+	Types []string `json:"Types,omitempty"`
+}
+
+//OptionError is an option failing the validation, named by its field in the version 1 export request
+type OptionError struct {
+	Name   string
+	Reason string
+}
+
+func (e OptionError) Error() string {
+	return e.Name + " " + e.Reason
+}
+
+//Validate checks the options with the rules of the export requests, whatever the job is created by, and defaults the days of a trending export
+func (o *Options) Validate() []OptionError {
+	var invalid []OptionError
+	fail := func(name, reason string, args ...interface{}) {
+		invalid = append(invalid, OptionError{Name: name, Reason: fmt.Sprintf(reason, args...)})
+	}
+	kind := o.Kind
+	if kind == "" {
+		kind = CONCEPTS
+	}
+	if kind != CONCEPTS && kind != COOCCURRENCES && kind != TRENDING {
+		fail("kind", "must be one of %v", []Kind{CONCEPTS, COOCCURRENCES, TRENDING})
+	}
+	applies := func(name string, set bool, to Kind) {
+		if set && kind != to {
+			fail(name, "only applies to the %v kind", to)
+		}
+	}
+	applies("options.annotations", o.Annotations, CONCEPTS)
+	applies("options.concordance", o.Concordance, CONCEPTS)
+	if o.Days < 0 {
+		fail("options.days", "must be an integer of at least 1")
+	}
+	applies("options.days", o.Days != 0, TRENDING)
+	applies("options.compare", o.Compare, TRENDING)
+	applies("filters.includeUnannotated", o.IncludeUnannotated, CONCEPTS)
+	if o.MinAnnotations < 0 {
+		fail("filters.minAnnotations", "must be an integer of at least 0")
+	}
+	applies("filters.minAnnotations", o.MinAnnotations != 0, CONCEPTS)
+	if o.MinCount < 0 {
+		fail("filters.minCount", "must be an integer of at least 0")
+	}
+	applies("filters.minCount", o.MinCount != 0, COOCCURRENCES)
+	if kind == TRENDING && o.Days == 0 {
+		o.Days = DefaultTrendingDays
+	}
+	return invalid
+}
+
+//TrendingExportType is the name of the file ranking the trending concepts of the given type
+func TrendingExportType(conceptType string) string {
+	return "Trending" + conceptType
+}
+
+//ExportTypes returns the names of the files exported for the given concept types
+func (o Options) ExportTypes(conceptTypes []string) []string {
+	var exportTypes []string
+	add := func(exportType string) {
+		if o.includes(exportType) {
+			exportTypes = append(exportTypes, exportType)
+		}
+	}
+	switch o.Kind {
+	case COOCCURRENCES:
+		add(string(COOCCURRENCES))
+		return exportTypes
+	case TRENDING:
+		for _, cType := range conceptTypes {
+			add(TrendingExportType(cType))
+		}
+		return exportTypes
+	}
+	for _, cType := range conceptTypes {
+		add(cType)
+	}
+	if o.Annotations {
+		add(Annotations)
+	}
+	if o.Concordance {
+		add(Concordance)
+	}
+	return exportTypes
+}
+
+func (o Options) includes(exportType string) bool {
+	if len(o.Types) == 0 {
+		return true
+	}
+	for _, t := range o.Types {
+		if t == exportType {
+			return true
+		}
+	}
+	return false
+}
+
 type Worker struct {
 	sync.RWMutex
-	ConceptCh    chan db.Concept `json:"-"`
-	Errch        chan error      `json:"-"`
-	ConceptType  string          `json:"ConceptType,omitempty"`
-	Count        int             `json:"Count,omitempty"`
-	Progress     int             `json:"Progress,omitempty"`
-	Status       State           `json:"Status,omitempty"`
-	ErrorMessage string          `json:"ErrorMessage,omitempty"`
+	RecordCh     chan db.Record `json:"-"`
+	Errch        chan error     `json:"-"`
+	ConceptType  string         `json:"ConceptType,omitempty"`
+	Count        int            `json:"Count,omitempty"`
+	Progress     int            `json:"Progress,omitempty"`
+	Status       State          `json:"Status,omitempty"`
+	ErrorMessage string         `json:"ErrorMessage,omitempty"`
+}
+
+func newWorker(exportType string) *Worker {
+	return &Worker{ConceptType: exportType, Errch: make(chan error, 2), RecordCh: make(chan db.Record), Status: STARTING}
 }
 
 func (w *Worker) setCount(count int) {
@@ -33,6 +175,12 @@ func (w *Worker) setCount(count int) {
 	w.Count = count
 }
 
+func (w *Worker) addCount(count int) {
+	w.Lock()
+	defer w.Unlock()
+	w.Count += count
+}
+
 func (w *Worker) GetCount() int {
 	w.Lock()
 	defer w.Unlock()
@@ -40,29 +188,155 @@ func (w *Worker) GetCount() int {
 }
 
 type Inquirer interface {
-	Inquire(candidates []string, tid string) []*Worker
+	Inquire(ctx context.Context, candidates []string, opts Options, tid string) []*Worker
+}
+
+//Counter counts what a job would export, e.g. to plan it
+type Counter interface {
+	Count(ctx context.Context, conceptType string, opts Options, tid string) (int, error)
+}
+
+//Previewer reads the first concepts of a type an export would read
+type Previewer interface {
+	Preview(ctx context.Context, conceptType string, opts Options, limit int, tid string) ([]db.Record, error)
+}
+
+//Streamer reads every concept of a type an export would read, handing them one by one to write
+type Streamer interface {
+	Stream(ctx context.Context, conceptType string, opts Options, write func(db.Record) error, tid string) (int, error)
 }
 
 type NeoInquirer struct {
-	Neo db.Service
-	Log *logger.UPPLogger
+	Neo      db.Service
+	PageSize int
+	Log      *logger.UPPLogger
 }
 
 func NewNeoInquirer(neo db.Service, log *logger.UPPLogger) *NeoInquirer {
-	return &NeoInquirer{Neo: neo, Log: log}
+	return &NeoInquirer{Neo: neo, PageSize: defaultPageSize, Log: log}
+}
+
+//Count returns the number of concepts of the given type an export with the options would read
+func (n *NeoInquirer) Count(ctx context.Context, conceptType string, opts Options, tid string) (count int, err error) {
+	_, span := tracing.StartFrom(ctx, tid, "NeoService.Count", attribute.String("concept_type", conceptType))
+	defer func() {
+		span.SetAttributes(attribute.Int("rows", count))
+		tracing.End(span, err)
+	}()
+	start := time.Now()
+	count, err = n.Neo.Count(conceptType, opts.ReadOptions)
+	monitoring.ObserveSince(monitoring.QueryDuration.WithLabelValues(conceptType), start)
+	return count, err
+}
+
+//Preview reads up to limit concepts of the given type with the query of the exports
+func (n *NeoInquirer) Preview(ctx context.Context, conceptType string, opts Options, limit int, tid string) (records []db.Record, err error) {
+	_, span := tracing.StartFrom(ctx, tid, "NeoInquirer.Preview", attribute.String("concept_type", conceptType), attribute.Int("limit", limit))
+	defer func() {
+		span.SetAttributes(attribute.Int("rows", len(records)))
+		tracing.End(span, err)
+	}()
+	readOpts := opts.ReadOptions
+	readOpts.Limit = limit
+	conceptCh := make(chan db.Concept)
+	start := time.Now()
+	_, _, err = n.Neo.Read(conceptType, readOpts, conceptCh)
+	monitoring.ObserveSince(monitoring.QueryDuration.WithLabelValues(conceptType), start)
+	records = []db.Record{}
+	for c := range conceptCh {
+		records = append(records, c)
+	}
+	return records, err
 }
 
-func (n *NeoInquirer) Inquire(candidates []string, tid string) []*Worker {
+//Stream reads the concepts of the given type with the query of the exports and writes them as they are read. Nothing is
+//written when the query fails, and the remaining concepts are dropped once a write fails
+func (n *NeoInquirer) Stream(ctx context.Context, conceptType string, opts Options, write func(db.Record) error, tid string) (count int, err error) {
+	_, span := tracing.StartFrom(ctx, tid, "NeoInquirer.Stream", attribute.String("concept_type", conceptType))
+	defer func() {
+		span.SetAttributes(attribute.Int("rows", count))
+		tracing.End(span, err)
+	}()
+	conceptCh := make(chan db.Concept)
+	start := time.Now()
+	_, _, err = n.Neo.Read(conceptType, opts.ReadOptions, conceptCh)
+	monitoring.ObserveSince(monitoring.QueryDuration.WithLabelValues(conceptType), start)
+	for c := range conceptCh {
+		if err != nil {
+			continue
+		}
+		if err = write(c); err == nil {
+			count++
+		}
+	}
+	return count, err
+}
+
+func (n *NeoInquirer) Inquire(ctx context.Context, candidates []string, opts Options, tid string) []*Worker {
+	//the span ends once every record was read
+	ctx, span := tracing.StartFrom(ctx, tid, "NeoInquirer.Inquire", attribute.StringSlice("concept_types", candidates), attribute.String("kind", string(opts.Kind)))
+	if opts.Kind == COOCCURRENCES {
+		if !opts.includes(string(COOCCURRENCES)) {
+			span.End()
+			return nil
+		}
+		worker := newWorker(string(COOCCURRENCES))
+		go func() {
+			defer span.End()
+			var readers []pageReader
+			for _, cType := range candidates {
+				readers = append(readers, n.coOccurrencesReader(cType, candidates, opts.MinCount))
+			}
+			n.inquirePages(ctx, worker, readers, tid)
+		}()
+		return []*Worker{worker}
+	}
+	if opts.Kind == TRENDING {
+		return n.inquireTrending(ctx, span, candidates, opts, tid)
+	}
 	var workers []*Worker
 	for _, cType := range candidates {
-		worker := &Worker{ConceptType: cType, Errch: make(chan error, 2), ConceptCh: make(chan db.Concept), Status: STARTING}
+		if opts.includes(cType) {
+			workers = append(workers, newWorker(cType))
+		}
+	}
+	conceptWorkers := workers
+	var pagedWorkers []pagedWorker
+	if opts.Annotations && opts.includes(Annotations) {
+		var readers []pageReader
+		for _, cType := range candidates {
+			readers = append(readers, n.annotationsReader(cType))
+		}
+		worker := newWorker(Annotations)
 		workers = append(workers, worker)
+		pagedWorkers = append(pagedWorkers, pagedWorker{worker: worker, readers: readers})
+	}
+	if opts.Concordance && opts.includes(Concordance) {
+		var readers []pageReader
+		for _, cType := range candidates {
+			readers = append(readers, n.concordanceReader(cType, opts.ReadOptions))
+		}
+		worker := newWorker(Concordance)
+		workers = append(workers, worker)
+		pagedWorkers = append(pagedWorkers, pagedWorker{worker: worker, readers: readers})
 	}
 	go func() {
+		defer span.End()
 		logEntry := n.Log.WithTransactionID(tid)
 		logEntry.Infof("Starting reading concepts from Neo: %v", candidates)
-		for _, worker := range workers {
-			count, found, err := n.Neo.Read(worker.ConceptType, worker.ConceptCh)
+		for _, worker := range conceptWorkers {
+			if err := ctx.Err(); err != nil {
+				worker.Errch <- err
+				continue
+			}
+			conceptCh := make(chan db.Concept)
+			go forwardConcepts(ctx, conceptCh, worker.RecordCh)
+			_, readSpan := tracing.StartFrom(ctx, tid, "NeoService.Read", attribute.String("concept_type", worker.ConceptType))
+			start := time.Now()
+			count, found, err := n.Neo.Read(worker.ConceptType, opts.ReadOptions, conceptCh)
+			monitoring.ObserveSince(monitoring.QueryDuration.WithLabelValues(worker.ConceptType), start)
+			readSpan.SetAttributes(attribute.Int("rows", count))
+			tracing.End(readSpan, err)
 			if err != nil {
 				logEntry.WithError(err).Errorf("error by reading %v concept type from Neo", worker.ConceptType)
 				worker.Errch <- err
@@ -77,7 +351,161 @@ func (n *NeoInquirer) Inquire(candidates []string, tid string) []*Worker {
 			logEntry.Infof("Found %v entries for %v concept", count, worker.ConceptType)
 			worker.setCount(count)
 		}
+		for _, pw := range pagedWorkers {
+			n.inquirePages(ctx, pw.worker, pw.readers, tid)
+		}
 		logEntry.Info("Finished Neo read")
 	}()
 	return workers
 }
+
+//inquireTrending ranks the concepts of every candidate type annotated by content published in the last days, compared to the days before when requested
+func (n *NeoInquirer) inquireTrending(ctx context.Context, span trace.Span, candidates []string, opts Options, tid string) []*Worker {
+	days := opts.Days
+	if days <= 0 {
+		days = DefaultTrendingDays
+	}
+	until := time.Now().UTC()
+	since := until.AddDate(0, 0, -days)
+	window := db.TrendingWindow{PreviousSince: since.Unix(), Since: since.Unix(), Until: until.Unix()}
+	if opts.Compare {
+		window.PreviousSince = since.AddDate(0, 0, -days).Unix()
+	}
+	var workers []*Worker
+	var readers []pageReader
+	for _, cType := range candidates {
+		if opts.includes(TrendingExportType(cType)) {
+			workers = append(workers, newWorker(TrendingExportType(cType)))
+			readers = append(readers, n.trendingReader(cType, window))
+		}
+	}
+	go func() {
+		defer span.End()
+		for i, worker := range workers {
+			n.inquirePages(ctx, worker, []pageReader{readers[i]}, tid)
+		}
+	}()
+	return workers
+}
+
+//pageReader reads the page of records following the ones it read before, skip being their count
+type pageReader func(skip, limit int) ([]db.Record, error)
+
+type pagedWorker struct {
+	worker  *Worker
+	readers []pageReader
+}
+
+//inquirePages streams the records of every reader page by page. The record channel is closed only when every page was read, so a failure is always reported through the error channel
+func (n *NeoInquirer) inquirePages(ctx context.Context, worker *Worker, readers []pageReader, tid string) {
+	logEntry := n.Log.WithTransactionID(tid)
+	for _, read := range readers {
+		for skip := 0; ; skip += n.PageSize {
+			if err := ctx.Err(); err != nil {
+				worker.Errch <- err
+				return
+			}
+			_, readSpan := tracing.StartFrom(ctx, tid, "NeoInquirer.readPage", attribute.String("export_type", worker.ConceptType), attribute.Int("skip", skip))
+			start := time.Now()
+			page, err := read(skip, n.PageSize)
+			monitoring.ObserveSince(monitoring.QueryDuration.WithLabelValues(worker.ConceptType), start)
+			readSpan.SetAttributes(attribute.Int("rows", len(page)))
+			tracing.End(readSpan, err)
+			if err != nil {
+				logEntry.WithError(err).Errorf("error by reading %v from Neo", worker.ConceptType)
+				worker.Errch <- err
+				return
+			}
+			worker.addCount(len(page))
+			for _, r := range page {
+				select {
+				case worker.RecordCh <- r:
+				case <-ctx.Done():
+					worker.Errch <- ctx.Err()
+					return
+				}
+			}
+			if len(page) < n.PageSize {
+				break
+			}
+		}
+	}
+	if worker.GetCount() == 0 {
+		err := fmt.Errorf("reading %v from Neo returned empty result", worker.ConceptType)
+		logEntry.Error(err)
+		worker.Errch <- err
+		return
+	}
+	logEntry.Infof("Found %v entries for %v", worker.GetCount(), worker.ConceptType)
+	close(worker.RecordCh)
+}
+
+//annotationsReader reads the pages by the key of their last content instead of skipping the previous ones
+func (n *NeoInquirer) annotationsReader(conceptType string) pageReader {
+	after := ""
+	return func(_, limit int) ([]db.Record, error) {
+		annotations, err := n.Neo.ReadAnnotations(conceptType, after, limit)
+		records := make([]db.Record, len(annotations))
+		for i, a := range annotations {
+			records[i] = a
+		}
+		if len(annotations) != 0 {
+			after = annotations[len(annotations)-1].ContentUuid
+		}
+		return records, err
+	}
+}
+
+//concordanceReader reads the pages by the key of their last source instead of skipping the previous ones
+func (n *NeoInquirer) concordanceReader(conceptType string, opts db.ReadOptions) pageReader {
+	var last db.Concordance
+	return func(_, limit int) ([]db.Record, error) {
+		concordances, err := n.Neo.ReadConcordance(conceptType, opts, last.PrefUuid, last.SourceUuid, limit)
+		records := make([]db.Record, len(concordances))
+		for i, c := range concordances {
+			records[i] = c
+		}
+		if len(concordances) != 0 {
+			last = concordances[len(concordances)-1]
+		}
+		return records, err
+	}
+}
+
+//coOccurrencesReader reads the pages by the key of their last pair instead of skipping the previous ones
+func (n *NeoInquirer) coOccurrencesReader(sourceType string, conceptTypes []string, minCount int) pageReader {
+	var last db.CoOccurrence
+	return func(_, limit int) ([]db.Record, error) {
+		coOccurrences, err := n.Neo.ReadCoOccurrences(sourceType, conceptTypes, minCount, last.SourceUuid, last.TargetUuid, limit)
+		records := make([]db.Record, len(coOccurrences))
+		for i, c := range coOccurrences {
+			records[i] = c
+		}
+		if len(coOccurrences) != 0 {
+			last = coOccurrences[len(coOccurrences)-1]
+		}
+		return records, err
+	}
+}
+
+func (n *NeoInquirer) trendingReader(conceptType string, window db.TrendingWindow) pageReader {
+	return func(skip, limit int) ([]db.Record, error) {
+		trending, err := n.Neo.ReadTrending(conceptType, window, skip, limit)
+		records := make([]db.Record, len(trending))
+		for i, c := range trending {
+			records[i] = c
+		}
+		return records, err
+	}
+}
+
+//forwardConcepts forwards the concepts read until the context is done, the remaining ones being dropped so the read finishes
+func forwardConcepts(ctx context.Context, conceptCh chan db.Concept, recordCh chan db.Record) {
+	defer close(recordCh)
+	for c := range conceptCh {
+		select {
+		case recordCh <- c:
+		case <-ctx.Done():
+		}
+	}
+}
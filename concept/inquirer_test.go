@@ -1,6 +1,7 @@
 package concept
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -16,11 +17,36 @@ type mockDbService struct {
 	mock.Mock
 }
 
-func (m *mockDbService) Read(conceptType string, conceptCh chan db.Concept) (int, bool, error) {
-	args := m.Called(conceptType, conceptCh)
+func (m *mockDbService) Read(conceptType string, opts db.ReadOptions, conceptCh chan db.Concept) (int, bool, error) {
+	args := m.Called(conceptType, opts, conceptCh)
 	return args.Int(0), args.Bool(1), args.Error(2)
 }
 
+func (m *mockDbService) Count(conceptType string, opts db.ReadOptions) (int, error) {
+	args := m.Called(conceptType, opts)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockDbService) ReadAnnotations(conceptType, after string, limit int) ([]db.Annotation, error) {
+	args := m.Called(conceptType, after, limit)
+	return args.Get(0).([]db.Annotation), args.Error(1)
+}
+
+func (m *mockDbService) ReadConcordance(conceptType string, opts db.ReadOptions, afterPrefUuid, afterSourceUuid string, limit int) ([]db.Concordance, error) {
+	args := m.Called(conceptType, opts, afterPrefUuid, afterSourceUuid, limit)
+	return args.Get(0).([]db.Concordance), args.Error(1)
+}
+
+func (m *mockDbService) ReadCoOccurrences(sourceType string, conceptTypes []string, minCount int, afterSourceUuid, afterTargetUuid string, limit int) ([]db.CoOccurrence, error) {
+	args := m.Called(sourceType, conceptTypes, minCount, afterSourceUuid, afterTargetUuid, limit)
+	return args.Get(0).([]db.CoOccurrence), args.Error(1)
+}
+
+func (m *mockDbService) ReadTrending(conceptType string, window db.TrendingWindow, skip, limit int) ([]db.TrendingConcept, error) {
+	args := m.Called(conceptType, window, skip, limit)
+	return args.Get(0).([]db.TrendingConcept), args.Error(1)
+}
+
 func TestNeoInquirer_InquireSuccessfully(t *testing.T) {
 	log := logger.NewUPPLogger("Test", "PANIC")
 
@@ -28,9 +54,9 @@ func TestNeoInquirer_InquireSuccessfully(t *testing.T) {
 	inquirer := NewNeoInquirer(mockDb, log)
 
 	cType := "Brand"
-	mockDb.On("Read", cType, mock.AnythingOfType("chan db.Concept")).Return(2, true, nil)
+	mockDb.On("Read", cType, db.ReadOptions{}, mock.AnythingOfType("chan db.Concept")).Return(2, true, nil)
 
-	workers := inquirer.Inquire([]string{cType}, "tid_1234")
+	workers := inquirer.Inquire(context.Background(), []string{cType}, Options{}, "tid_1234")
 
 	time.Sleep(500 * time.Millisecond)
 
@@ -49,9 +75,9 @@ func TestNeoInquirer_InquireSuccessfullyWithEmptyResult(t *testing.T) {
 	inquirer := NewNeoInquirer(mockDb, log)
 
 	cType := "Brand"
-	mockDb.On("Read", cType, mock.AnythingOfType("chan db.Concept")).Return(0, false, nil)
+	mockDb.On("Read", cType, db.ReadOptions{}, mock.AnythingOfType("chan db.Concept")).Return(0, false, nil)
 
-	workers := inquirer.Inquire([]string{cType}, "tid_1234")
+	workers := inquirer.Inquire(context.Background(), []string{cType}, Options{}, "tid_1234")
 
 	time.Sleep(500 * time.Millisecond)
 
@@ -71,9 +97,9 @@ func TestNeoInquirer_InquireWithError(t *testing.T) {
 	inquirer := NewNeoInquirer(mockDb, log)
 
 	cType := "Brand"
-	mockDb.On("Read", cType, mock.AnythingOfType("chan db.Concept")).Return(0, false, errors.New("Neo err"))
+	mockDb.On("Read", cType, db.ReadOptions{}, mock.AnythingOfType("chan db.Concept")).Return(0, false, errors.New("Neo err"))
 
-	workers := inquirer.Inquire([]string{cType}, "tid_1234")
+	workers := inquirer.Inquire(context.Background(), []string{cType}, Options{}, "tid_1234")
 
 	time.Sleep(500 * time.Millisecond)
 
@@ -85,3 +111,312 @@ func TestNeoInquirer_InquireWithError(t *testing.T) {
 	assert.Equal(t, "Neo err", (<-workers[0].Errch).Error())
 	mockDb.AssertExpectations(t)
 }
+
+func TestNeoInquirer_InquireAnnotationsInPages(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+	inquirer.PageSize = 2
+
+	cType := "Brand"
+	annotation := func(contentUUID string) db.Annotation {
+		return db.Annotation{ContentUuid: contentUUID, ConceptUuid: "concept", Predicate: "mentions", ConceptType: cType}
+	}
+	mockDb.On("Read", cType, db.ReadOptions{}, mock.AnythingOfType("chan db.Concept")).Return(1, true, nil)
+	mockDb.On("ReadAnnotations", cType, "", 2).Return([]db.Annotation{annotation("content1"), annotation("content2")}, nil)
+	mockDb.On("ReadAnnotations", cType, "content2", 2).Return([]db.Annotation{annotation("content3")}, nil)
+
+	workers := inquirer.Inquire(context.Background(), []string{cType}, Options{Annotations: true}, "tid_1234")
+
+	assert.Equal(t, 2, len(workers))
+	assert.Equal(t, Annotations, workers[1].ConceptType)
+	var records []db.Record
+	for r := range workers[1].RecordCh {
+		records = append(records, r)
+	}
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, 3, workers[1].GetCount())
+	assert.Equal(t, 0, len(workers[1].Errch))
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_InquireAnnotationsWithError(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+
+	cType := "Brand"
+	mockDb.On("Read", cType, db.ReadOptions{}, mock.AnythingOfType("chan db.Concept")).Return(1, true, nil)
+	mockDb.On("ReadAnnotations", cType, "", defaultPageSize).Return([]db.Annotation{}, errors.New("Neo err"))
+
+	workers := inquirer.Inquire(context.Background(), []string{cType}, Options{Annotations: true}, "tid_1234")
+
+	select {
+	case err := <-workers[1].Errch:
+		assert.Equal(t, "Neo err", err.Error())
+	case <-time.After(3 * time.Second):
+		t.FailNow()
+	}
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_InquireCancelled(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	workers := inquirer.Inquire(ctx, []string{"Brand"}, Options{Annotations: true}, "tid_1234")
+
+	for _, worker := range workers {
+		select {
+		case err := <-worker.Errch:
+			assert.Equal(t, context.Canceled, err)
+		case <-time.After(3 * time.Second):
+			t.FailNow()
+		}
+	}
+	mockDb.AssertNotCalled(t, "Read", mock.Anything, mock.Anything, mock.Anything)
+	mockDb.AssertNotCalled(t, "ReadAnnotations", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNeoInquirer_InquireConcordanceWithReadOptions(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+
+	cTypes := []string{"Brand", "Person"}
+	opts := Options{ReadOptions: db.ReadOptions{MinAnnotations: 3}, Concordance: true}
+	brandSource := db.Concordance{PrefUuid: "brand", SourceUuid: "brand-tme", Authority: "TME", ConceptType: "Brand"}
+	personSource := db.Concordance{PrefUuid: "person", SourceUuid: "person-wikidata", Authority: "Wikidata", ConceptType: "Person"}
+	for _, cType := range cTypes {
+		mockDb.On("Read", cType, opts.ReadOptions, mock.AnythingOfType("chan db.Concept")).Return(1, true, nil)
+	}
+	mockDb.On("ReadConcordance", "Brand", opts.ReadOptions, "", "", defaultPageSize).Return([]db.Concordance{brandSource}, nil)
+	mockDb.On("ReadConcordance", "Person", opts.ReadOptions, "", "", defaultPageSize).Return([]db.Concordance{personSource}, nil)
+
+	workers := inquirer.Inquire(context.Background(), cTypes, opts, "tid_1234")
+
+	assert.Equal(t, 3, len(workers))
+	assert.Equal(t, Concordance, workers[2].ConceptType)
+	var records []db.Record
+	for r := range workers[2].RecordCh {
+		records = append(records, r)
+	}
+	assert.Equal(t, []db.Record{brandSource, personSource}, records)
+	assert.Equal(t, 2, workers[2].GetCount())
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_InquireConcordanceInPages(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+	inquirer.PageSize = 2
+
+	source := func(prefUUID, sourceUUID string) db.Concordance {
+		return db.Concordance{PrefUuid: prefUUID, SourceUuid: sourceUUID, ConceptType: "Brand"}
+	}
+	mockDb.On("Read", "Brand", db.ReadOptions{}, mock.AnythingOfType("chan db.Concept")).Return(1, true, nil)
+	mockDb.On("ReadConcordance", "Brand", db.ReadOptions{}, "", "", 2).Return([]db.Concordance{source("brand1", "tme1"), source("brand1", "tme2")}, nil)
+	mockDb.On("ReadConcordance", "Brand", db.ReadOptions{}, "brand1", "tme2", 2).Return([]db.Concordance{source("brand2", "tme3")}, nil)
+
+	workers := inquirer.Inquire(context.Background(), []string{"Brand"}, Options{Concordance: true}, "tid_1234")
+
+	assert.Equal(t, 2, len(workers))
+	var records []db.Record
+	for r := range workers[1].RecordCh {
+		records = append(records, r)
+	}
+	assert.Equal(t, 3, len(records))
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_InquireCoOccurrences(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+
+	cTypes := []string{"Organisation", "Person"}
+	pair := db.CoOccurrence{SourceUuid: "org", SourceType: "Organisation", TargetUuid: "person", TargetType: "Person", Count: 4}
+	inquirer.PageSize = 1
+	mockDb.On("ReadCoOccurrences", "Organisation", cTypes, 2, "", "", 1).Return([]db.CoOccurrence{pair}, nil)
+	mockDb.On("ReadCoOccurrences", "Organisation", cTypes, 2, "org", "person", 1).Return([]db.CoOccurrence{}, nil)
+	mockDb.On("ReadCoOccurrences", "Person", cTypes, 2, "", "", 1).Return([]db.CoOccurrence{}, nil)
+
+	workers := inquirer.Inquire(context.Background(), cTypes, Options{Kind: COOCCURRENCES, MinCount: 2}, "tid_1234")
+
+	assert.Equal(t, 1, len(workers))
+	assert.Equal(t, string(COOCCURRENCES), workers[0].ConceptType)
+	var records []db.Record
+	for r := range workers[0].RecordCh {
+		records = append(records, r)
+	}
+	assert.Equal(t, []db.Record{pair}, records)
+	assert.Equal(t, 1, workers[0].GetCount())
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_InquireTrendingComparedToPreviousWindow(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+
+	trending := db.TrendingConcept{Rank: 1, Uuid: "person", Count: 6, PreviousCount: 4}
+	var window db.TrendingWindow
+	mockDb.On("ReadTrending", "Person", mock.AnythingOfType("db.TrendingWindow"), 0, defaultPageSize).
+		Run(func(args mock.Arguments) { window = args.Get(1).(db.TrendingWindow) }).
+		Return([]db.TrendingConcept{trending}, nil)
+
+	workers := inquirer.Inquire(context.Background(), []string{"Person"}, Options{Kind: TRENDING, Days: 7, Compare: true}, "tid_1234")
+
+	assert.Equal(t, 1, len(workers))
+	assert.Equal(t, "TrendingPerson", workers[0].ConceptType)
+	var records []db.Record
+	for r := range workers[0].RecordCh {
+		records = append(records, r)
+	}
+	assert.Equal(t, []db.Record{trending}, records)
+	assert.Equal(t, int64(7*24*60*60), window.Until-window.Since)
+	assert.Equal(t, int64(7*24*60*60), window.Since-window.PreviousSince)
+	assert.Equal(t, "0.5000", records[0].Value("growth"))
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_InquireTrendingWithoutDays(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+
+	var window db.TrendingWindow
+	mockDb.On("ReadTrending", "Person", mock.AnythingOfType("db.TrendingWindow"), 0, defaultPageSize).
+		Run(func(args mock.Arguments) { window = args.Get(1).(db.TrendingWindow) }).
+		Return([]db.TrendingConcept{{Rank: 1, Uuid: "person", Count: 2}}, nil)
+
+	workers := inquirer.Inquire(context.Background(), []string{"Person"}, Options{Kind: TRENDING, Days: -1}, "tid_1234")
+
+	assert.Equal(t, 1, len(workers))
+	for range workers[0].RecordCh {
+	}
+	assert.Equal(t, int64(DefaultTrendingDays*24*60*60), window.Until-window.Since)
+	assert.Equal(t, window.Since, window.PreviousSince)
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_InquireOnlyTypes(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, log)
+
+	cTypes := []string{"Brand", "Person"}
+	opts := Options{Concordance: true, Types: []string{"Person", Concordance}}
+	personSource := db.Concordance{PrefUuid: "person", SourceUuid: "person-wikidata", Authority: "Wikidata", ConceptType: "Person"}
+	mockDb.On("Read", "Person", opts.ReadOptions, mock.AnythingOfType("chan db.Concept")).Return(1, true, nil)
+	mockDb.On("ReadConcordance", "Brand", opts.ReadOptions, "", "", defaultPageSize).Return([]db.Concordance{}, nil)
+	mockDb.On("ReadConcordance", "Person", opts.ReadOptions, "", "", defaultPageSize).Return([]db.Concordance{personSource}, nil)
+
+	workers := inquirer.Inquire(context.Background(), cTypes, opts, "tid_1234")
+
+	assert.Equal(t, []string{"Person", Concordance}, opts.ExportTypes(cTypes))
+	assert.Equal(t, 2, len(workers))
+	assert.Equal(t, "Person", workers[0].ConceptType)
+	assert.Equal(t, Concordance, workers[1].ConceptType)
+	for range workers[1].RecordCh {
+	}
+	assert.Equal(t, 1, workers[1].GetCount())
+	mockDb.AssertExpectations(t)
+	mockDb.AssertNotCalled(t, "Read", "Brand", mock.Anything, mock.Anything)
+}
+
+func TestNeoInquirer_Count(t *testing.T) {
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, logger.NewUPPLogger("Test", "PANIC"))
+	opts := Options{ReadOptions: db.ReadOptions{MinAnnotations: 5}}
+	mockDb.On("Count", "Brand", opts.ReadOptions).Return(42, nil)
+	mockDb.On("Count", "Person", opts.ReadOptions).Return(0, errors.New("Neo err"))
+
+	count, err := inquirer.Count(context.Background(), "Brand", opts, "tid_1234")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, count)
+	_, err = inquirer.Count(context.Background(), "Person", opts, "tid_1234")
+	assert.EqualError(t, err, "Neo err")
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_Preview(t *testing.T) {
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, logger.NewUPPLogger("Test", "PANIC"))
+	brand := db.Concept{Uuid: "brand", PrefLabel: "Brand"}
+	mockDb.On("Read", "Brand", db.ReadOptions{IncludeUnannotated: true, Limit: 2}, mock.AnythingOfType("chan db.Concept")).Return(1, true, nil).
+		Run(func(args mock.Arguments) {
+			conceptCh := args.Get(2).(chan db.Concept)
+			go func() {
+				conceptCh <- brand
+				close(conceptCh)
+			}()
+		})
+
+	records, err := inquirer.Preview(context.Background(), "Brand", Options{ReadOptions: db.ReadOptions{IncludeUnannotated: true}}, 2, "tid_1234")
+	assert.NoError(t, err)
+	assert.Equal(t, []db.Record{brand}, records)
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_Stream(t *testing.T) {
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, logger.NewUPPLogger("Test", "PANIC"))
+	concepts := []db.Concept{{Uuid: "brand1"}, {Uuid: "brand2"}, {Uuid: "brand3"}}
+	mockDb.On("Read", "Brand", db.ReadOptions{}, mock.AnythingOfType("chan db.Concept")).Return(3, true, nil).
+		Run(func(args mock.Arguments) {
+			conceptCh := args.Get(2).(chan db.Concept)
+			go func() {
+				for _, c := range concepts {
+					conceptCh <- c
+				}
+				close(conceptCh)
+			}()
+		})
+
+	var written []db.Record
+	count, err := inquirer.Stream(context.Background(), "Brand", Options{}, func(r db.Record) error {
+		written = append(written, r)
+		return nil
+	}, "tid_1234")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []db.Record{concepts[0], concepts[1], concepts[2]}, written)
+
+	//a failed write drops the remaining concepts
+	count, err = inquirer.Stream(context.Background(), "Brand", Options{}, func(r db.Record) error {
+		return errors.New("client gone")
+	}, "tid_1234")
+	assert.EqualError(t, err, "client gone")
+	assert.Equal(t, 0, count)
+	mockDb.AssertExpectations(t)
+}
+
+func TestNeoInquirer_StreamReadFailure(t *testing.T) {
+	mockDb := new(mockDbService)
+	inquirer := NewNeoInquirer(mockDb, logger.NewUPPLogger("Test", "PANIC"))
+	mockDb.On("Read", "Brand", db.ReadOptions{}, mock.AnythingOfType("chan db.Concept")).Return(0, false, errors.New("neo down")).
+		Run(func(args mock.Arguments) {
+			close(args.Get(2).(chan db.Concept))
+		})
+
+	count, err := inquirer.Stream(context.Background(), "Brand", Options{}, func(r db.Record) error {
+		t.Error("nothing should be written")
+		return nil
+	}, "tid_1234")
+	assert.EqualError(t, err, "neo down")
+	assert.Equal(t, 0, count)
+}
@@ -2,20 +2,39 @@ package concept
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+
+	"github.com/Financial-Times/concept-exporter/tracing"
+	"github.com/pborman/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	s3WriterPath    = "/concept/"
+	preflightPrefix = "preflight/"
 )
 
-const s3WriterPath = "/concept/"
+//ErrNotFound is returned when fetching a file that was never uploaded
+var ErrNotFound = errors.New("file not found")
 
 type Client interface {
 	Do(req *http.Request) (resp *http.Response, err error)
 }
 
 type Updater interface {
-	Upload(concept []byte, conceptType, tid string) error
+	Upload(ctx context.Context, concept []byte, conceptType, tid string) error
+}
+
+//Fetcher downloads previously uploaded files
+type Fetcher interface {
+	Fetch(fileName, tid string) ([]byte, error)
 }
 
 type S3Updater struct {
@@ -24,9 +43,13 @@ type S3Updater struct {
 	S3WriterHealthURL string
 }
 
-func (u *S3Updater) Upload(concept []byte, fileName, tid string) error {
+func (u *S3Updater) Upload(ctx context.Context, concept []byte, fileName, tid string) (err error) {
+	ctx, span := tracing.StartFrom(ctx, tid, "S3Updater.Upload", attribute.String("file_name", fileName), attribute.Int("bytes", len(concept)))
+	defer func() {
+		tracing.End(span, err)
+	}()
 	buf := new(bytes.Buffer)
-	_, err := buf.Write(concept)
+	_, err = buf.Write(concept)
 	if err != nil {
 		return err
 	}
@@ -38,6 +61,7 @@ func (u *S3Updater) Upload(concept []byte, fileName, tid string) error {
 	req.Header.Add("User-Agent", "UPP Concept Exporter")
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("X-Request-Id", tid)
+	tracing.Inject(ctx, req)
 
 	resp, err := u.Client.Do(req)
 	if err != nil {
@@ -51,6 +75,29 @@ func (u *S3Updater) Upload(concept []byte, fileName, tid string) error {
 	return nil
 }
 
+func (u *S3Updater) Fetch(fileName, tid string) ([]byte, error) {
+	req, err := http.NewRequest("GET", u.S3WriterBaseURL+s3WriterPath+fileName, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", "UPP Concept Exporter")
+	req.Header.Add("X-Request-Id", tid)
+
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("UPP Export RW S3 returned HTTP %v", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
 func (u *S3Updater) CheckHealth(client Client) (string, error) {
 	req, err := http.NewRequest("GET", u.S3WriterHealthURL, nil)
 	if err != nil {
@@ -70,3 +117,76 @@ func (u *S3Updater) CheckHealth(client Client) (string, error) {
 	}
 	return "S3 Writer is good to go.", nil
 }
+
+//CheckSink reads the health of the S3 writer, whose checks cover its access to the bucket the files are written to, without writing anything
+func (u *S3Updater) CheckSink(client Client, tid string) (string, error) {
+	req, err := http.NewRequest("GET", u.S3WriterBaseURL+"/__health", nil)
+	if err != nil {
+		return "Error in building request to check the S3 Writer health", err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("X-Request-Id", tid)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "Error in getting request to check the S3 Writer health.", err
+	}
+	defer resp.Body.Close()
+	var health struct {
+		Ok     bool `json:"ok"`
+		Checks []struct {
+			Name        string `json:"name"`
+			Ok          bool   `json:"ok"`
+			CheckOutput string `json:"checkOutput"`
+		} `json:"checks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return "S3 Writer health is unreadable.", fmt.Errorf("health HTTP status code is %v: %v", resp.StatusCode, err)
+	}
+	if !health.Ok {
+		var failing []string
+		for _, c := range health.Checks {
+			if !c.Ok {
+				failing = append(failing, fmt.Sprintf("%v: %v", c.Name, c.CheckOutput))
+			}
+		}
+		return "S3 Writer can't write to its bucket.", fmt.Errorf("failing health checks: %v", strings.Join(failing, "; "))
+	}
+	return "S3 Writer can write to its bucket.", nil
+}
+
+//ProbeSink writes a probe file under the preflight/ prefix through the S3 writer and deletes it, so the bucket is known to take the files.
+//Unlike CheckSink it writes to the bucket, so it's only run by the dry runs when enabled
+func (u *S3Updater) ProbeSink(client Client, tid string) (string, error) {
+	fileName := preflightPrefix + uuid.New()
+	if err := u.probe(client, http.MethodPut, fileName, []byte("preflight"), tid); err != nil {
+		return "S3 Writer can't write to its bucket.", err
+	}
+	if err := u.probe(client, http.MethodDelete, fileName, nil, tid); err != nil {
+		return "S3 Writer can't delete from its bucket.", err
+	}
+	return "S3 Writer can write to its bucket.", nil
+}
+
+func (u *S3Updater) probe(client Client, method, fileName string, content []byte, tid string) error {
+	req, err := http.NewRequest(method, u.S3WriterBaseURL+s3WriterPath+fileName, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("User-Agent", "UPP Concept Exporter")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Request-Id", tid)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%v of %v returned HTTP %v", method, fileName, resp.StatusCode)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package concept
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -47,6 +48,22 @@ func (m *mockS3WriterServer) startMockS3WriterServer(t *testing.T) *httptest.Ser
 
 	}).Methods(http.MethodPut)
 
+	router.HandleFunc("/concept/{fileName}", func(w http.ResponseWriter, r *http.Request) {
+		status, body := m.FetchRequest(mux.Vars(r)["fileName"], r.Header.Get("X-Request-Id"))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc("/concept/preflight/{fileName}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(m.ProbeRequest(r.Method, r.Header.Get("X-Request-Id")))
+	}).Methods(http.MethodPut, http.MethodDelete)
+
+	router.HandleFunc("/__health", func(w http.ResponseWriter, r *http.Request) {
+		status, body := m.Health(r.Header.Get("X-Request-Id"))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}).Methods(http.MethodGet)
+
 	router.HandleFunc("/__gtg", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(m.GTG())
 	}).Methods(http.MethodGet)
@@ -59,11 +76,26 @@ func (m *mockS3WriterServer) GTG() int {
 	return args.Int(0)
 }
 
+func (m *mockS3WriterServer) ProbeRequest(method, tid string) int {
+	args := m.Called(method, tid)
+	return args.Int(0)
+}
+
+func (m *mockS3WriterServer) Health(tid string) (int, string) {
+	args := m.Called(tid)
+	return args.Int(0), args.String(1)
+}
+
 func (m *mockS3WriterServer) UploadRequest(fileName, tid, contentTypeHeader string) int {
 	args := m.Called(fileName, tid, contentTypeHeader)
 	return args.Int(0)
 }
 
+func (m *mockS3WriterServer) FetchRequest(fileName, tid string) (int, string) {
+	args := m.Called(fileName, tid)
+	return args.Int(0), args.String(1)
+}
+
 func TestS3UpdaterUploadConcept(t *testing.T) {
 	testConcept := "Brand"
 
@@ -73,7 +105,7 @@ func TestS3UpdaterUploadConcept(t *testing.T) {
 
 	updater := NewS3Updater(server.URL)
 
-	err := updater.Upload([]byte("test"), testConcept+".csv", "tid_1234")
+	err := updater.Upload(context.Background(), []byte("test"), testConcept+".csv", "tid_1234")
 	assert.NoError(t, err)
 	mockServer.AssertExpectations(t)
 }
@@ -87,7 +119,7 @@ func TestS3UpdaterUploadContentErrorResponse(t *testing.T) {
 
 	updater := NewS3Updater(server.URL)
 
-	err := updater.Upload([]byte("test"), testConcept+".csv", "tid_1234")
+	err := updater.Upload(context.Background(), []byte("test"), testConcept+".csv", "tid_1234")
 	assert.Error(t, err)
 	assert.Equal(t, "UPP Export RW S3 returned HTTP 503", err.Error())
 	mockServer.AssertExpectations(t)
@@ -96,7 +128,7 @@ func TestS3UpdaterUploadContentErrorResponse(t *testing.T) {
 func TestS3UpdaterUploadContentWithErrorOnNewRequest(t *testing.T) {
 	updater := NewS3Updater("://")
 
-	err := updater.Upload([]byte("test"), "Brand.csv", "tid_1234")
+	err := updater.Upload(context.Background(), []byte("test"), "Brand.csv", "tid_1234")
 	var urlError *url.Error
 	assert.True(t, errors.As(err, &urlError))
 	assert.Equal(t, err.(*url.Error).Op, "parse")
@@ -110,12 +142,50 @@ func TestS3UpdaterUploadContentErrorOnRequestDo(t *testing.T) {
 		S3WriterBaseURL: "http://server",
 	}
 
-	err := updater.Upload([]byte("test"), "Brand.csv", "tid_1234")
+	err := updater.Upload(context.Background(), []byte("test"), "Brand.csv", "tid_1234")
 	assert.Error(t, err)
 	assert.Equal(t, "Http Client err", err.Error())
 	mockClient.AssertExpectations(t)
 }
 
+func TestS3UpdaterFetch(t *testing.T) {
+	mockServer := new(mockS3WriterServer)
+	mockServer.On("FetchRequest", "Brand.csv", "tid_1234").Return(200, "test")
+	server := mockServer.startMockS3WriterServer(t)
+
+	updater := NewS3Updater(server.URL)
+
+	content, err := updater.(*S3Updater).Fetch("Brand.csv", "tid_1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", string(content))
+	mockServer.AssertExpectations(t)
+}
+
+func TestS3UpdaterFetchErrorResponse(t *testing.T) {
+	mockServer := new(mockS3WriterServer)
+	mockServer.On("FetchRequest", "Brand.csv", "tid_1234").Return(503, "")
+	server := mockServer.startMockS3WriterServer(t)
+
+	updater := NewS3Updater(server.URL)
+
+	_, err := updater.(*S3Updater).Fetch("Brand.csv", "tid_1234")
+	assert.Error(t, err)
+	assert.Equal(t, "UPP Export RW S3 returned HTTP 503", err.Error())
+	mockServer.AssertExpectations(t)
+}
+
+func TestS3UpdaterFetchNotFound(t *testing.T) {
+	mockServer := new(mockS3WriterServer)
+	mockServer.On("FetchRequest", "Brand.csv", "tid_1234").Return(404, "")
+	server := mockServer.startMockS3WriterServer(t)
+
+	updater := NewS3Updater(server.URL)
+
+	_, err := updater.(*S3Updater).Fetch("Brand.csv", "tid_1234")
+	assert.Equal(t, ErrNotFound, err)
+	mockServer.AssertExpectations(t)
+}
+
 func TestS3UpdaterCheckHealth(t *testing.T) {
 	mockServer := new(mockS3WriterServer)
 	mockServer.On("GTG").Return(200)
@@ -142,6 +212,76 @@ func TestS3UpdaterCheckHealthError(t *testing.T) {
 	mockServer.AssertExpectations(t)
 }
 
+func TestS3UpdaterCheckSink(t *testing.T) {
+	mockServer := new(mockS3WriterServer)
+	mockServer.On("Health", "tid_1234").Return(200, `{"ok":true,"checks":[{"name":"S3 Bucket check","ok":true}]}`)
+	server := mockServer.startMockS3WriterServer(t)
+
+	updater := NewS3Updater(server.URL)
+
+	resp, err := updater.(*S3Updater).CheckSink(&http.Client{}, "tid_1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "S3 Writer can write to its bucket.", resp)
+	mockServer.AssertExpectations(t)
+}
+
+func TestS3UpdaterCheckSinkFailingChecks(t *testing.T) {
+	mockServer := new(mockS3WriterServer)
+	mockServer.On("Health", "tid_1234").Return(200, `{"ok":false,"checks":[{"name":"S3 Bucket check","ok":false,"checkOutput":"Access Denied"},{"name":"Other","ok":true}]}`)
+	server := mockServer.startMockS3WriterServer(t)
+
+	updater := NewS3Updater(server.URL)
+
+	resp, err := updater.(*S3Updater).CheckSink(&http.Client{}, "tid_1234")
+	assert.EqualError(t, err, "failing health checks: S3 Bucket check: Access Denied")
+	assert.Equal(t, "S3 Writer can't write to its bucket.", resp)
+	mockServer.AssertExpectations(t)
+}
+
+func TestS3UpdaterProbeSink(t *testing.T) {
+	mockServer := new(mockS3WriterServer)
+	mockServer.On("ProbeRequest", http.MethodPut, "tid_1234").Return(200).Once()
+	mockServer.On("ProbeRequest", http.MethodDelete, "tid_1234").Return(204).Once()
+	server := mockServer.startMockS3WriterServer(t)
+
+	updater := NewS3Updater(server.URL)
+
+	resp, err := updater.(*S3Updater).ProbeSink(&http.Client{}, "tid_1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "S3 Writer can write to its bucket.", resp)
+	mockServer.AssertExpectations(t)
+}
+
+func TestS3UpdaterProbeSinkWriteError(t *testing.T) {
+	mockServer := new(mockS3WriterServer)
+	mockServer.On("ProbeRequest", http.MethodPut, "tid_1234").Return(403).Once()
+	server := mockServer.startMockS3WriterServer(t)
+
+	updater := NewS3Updater(server.URL)
+
+	resp, err := updater.(*S3Updater).ProbeSink(&http.Client{}, "tid_1234")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "returned HTTP 403")
+	assert.Equal(t, "S3 Writer can't write to its bucket.", resp)
+	mockServer.AssertExpectations(t)
+	mockServer.AssertNotCalled(t, "ProbeRequest", http.MethodDelete, "tid_1234")
+}
+
+func TestS3UpdaterProbeSinkDeleteError(t *testing.T) {
+	mockServer := new(mockS3WriterServer)
+	mockServer.On("ProbeRequest", http.MethodPut, "tid_1234").Return(200).Once()
+	mockServer.On("ProbeRequest", http.MethodDelete, "tid_1234").Return(500).Once()
+	server := mockServer.startMockS3WriterServer(t)
+
+	updater := NewS3Updater(server.URL)
+
+	resp, err := updater.(*S3Updater).ProbeSink(&http.Client{}, "tid_1234")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "returned HTTP 500")
+	assert.Equal(t, "S3 Writer can't delete from its bucket.", resp)
+	mockServer.AssertExpectations(t)
+}
+
 func TestS3UpdaterCheckHealthErrorOnNewRequest(t *testing.T) {
 	updater := &S3Updater{
 		S3WriterHealthURL: "://",
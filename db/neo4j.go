@@ -2,6 +2,7 @@ package db
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/Financial-Times/neo-model-utils-go/mapper"
 	"github.com/Financial-Times/neo-utils-go/v2/neoutils"
@@ -10,7 +11,17 @@ import (
 
 //Service reads from a data source and uses a channel to iterate on the retrieved values for the given concept type
 type Service interface {
-	Read(conceptType string, conceptCh chan Concept) (int, bool, error)
+	Read(conceptType string, opts ReadOptions, conceptCh chan Concept) (int, bool, error)
+	Count(conceptType string, opts ReadOptions) (int, error)
+	ReadAnnotations(conceptType, after string, limit int) ([]Annotation, error)
+	ReadConcordance(conceptType string, opts ReadOptions, afterPrefUuid, afterSourceUuid string, limit int) ([]Concordance, error)
+	ReadCoOccurrences(sourceType string, conceptTypes []string, minCount int, afterSourceUuid, afterTargetUuid string, limit int) ([]CoOccurrence, error)
+	ReadTrending(conceptType string, window TrendingWindow, skip, limit int) ([]TrendingConcept, error)
+}
+
+//Record is a single exported row whose values are looked up by column name
+type Record interface {
+	Value(column string) string
 }
 
 //NeoService is the implementation of Service for Neo4j
@@ -34,41 +45,200 @@ type Concept struct {
 	LeiCode   string
 	FactsetId string
 	FIGI      string
+	Annotated bool
 }
 
-func (s *NeoService) Read(conceptType string, conceptCh chan Concept) (int, bool, error) {
-	results := []Concept{}
-	stmt := fmt.Sprintf(`
-		MATCH (x:%s)<-[:EQUIVALENT_TO]-(:Concept)<-[:MENTIONS|MAJOR_MENTIONS|ABOUT|IS_CLASSIFIED_BY|IS_PRIMARILY_CLASSIFIED_BY|HAS_AUTHOR|HAS_BRAND]-(:Content)
-		USING SCAN x:%[1]s
-		RETURN DISTINCT x.prefUUID AS Uuid, x.prefLabel AS PrefLabel, labels(x) AS Labels
-		`, conceptType)
+func (c Concept) Value(column string) string {
+	switch column {
+	case "id":
+		return c.Id
+	case "prefLabel":
+		return c.PrefLabel
+	case "apiUrl":
+		return c.ApiUrl
+	case "leiCode":
+		return c.LeiCode
+	case "factsetId":
+		return c.FactsetId
+	case "FIGI":
+		return c.FIGI
+	case "annotated":
+		return strconv.FormatBool(c.Annotated)
+	}
+	return ""
+}
 
-	if conceptType == "Organisation" {
-		stmt = `
-		MATCH (:Content)-[:MENTIONS|MAJOR_MENTIONS|ABOUT|IS_CLASSIFIED_BY|IS_PRIMARILY_CLASSIFIED_BY|HAS_AUTHOR]->()-[:EQUIVALENT_TO]->(x:Organisation)
-		USING SCAN x:Organisation
-		WITH DISTINCT x
-		MATCH (x)<-[:EQUIVALENT_TO]-(concept)
-		OPTIONAL MATCH (concept)<-[:ISSUED_BY]-(fi:FinancialInstrument)
-		WITH x, collect(DISTINCT CASE concept.authority WHEN 'FACTSET' THEN concept.authorityValue END) AS factsetIds,
-			collect(DISTINCT fi.figiCode) as figiCodes 
-		RETURN x.prefUUID AS Uuid, labels(x) AS Labels, x.prefLabel AS PrefLabel, x.leiCode AS leiCode,
-			reduce(s=head(factsetIds), n IN tail(factsetIds) | s + ';' + n) AS factsetId,
-			reduce(s=head(figiCodes), n IN tail(figiCodes) | s + ';' + n) AS FIGI
-		`
+//Annotation is the model for an annotation between a content and a canonical concept
+type Annotation struct {
+	ContentUuid string
+	ConceptId   string
+	ConceptUuid string
+	Predicate   string
+	ConceptType string
+}
+
+func (a Annotation) Value(column string) string {
+	switch column {
+	case "contentUUID":
+		return a.ContentUuid
+	case "conceptId":
+		return a.ConceptId
+	case "predicate":
+		return a.Predicate
+	case "conceptType":
+		return a.ConceptType
+	}
+	return ""
+}
+
+//Concordance is the model for a source concept equivalent to a canonical concept
+type Concordance struct {
+	ConceptId      string
+	PrefUuid       string
+	SourceUuid     string
+	Authority      string
+	AuthorityValue string
+	ConceptType    string
+}
+
+func (c Concordance) Value(column string) string {
+	switch column {
+	case "conceptId":
+		return c.ConceptId
+	case "prefUUID":
+		return c.PrefUuid
+	case "sourceUUID":
+		return c.SourceUuid
+	case "authority":
+		return c.Authority
+	case "authorityValue":
+		return c.AuthorityValue
+	case "conceptType":
+		return c.ConceptType
+	}
+	return ""
+}
+
+//CoOccurrence is the model for a pair of canonical concepts annotated by the same content
+type CoOccurrence struct {
+	SourceUuid      string
+	SourceId        string
+	SourcePrefLabel string
+	SourceType      string
+	TargetUuid      string
+	TargetId        string
+	TargetPrefLabel string
+	TargetType      string
+	Count           int
+}
+
+func (c CoOccurrence) Value(column string) string {
+	switch column {
+	case "sourceId":
+		return c.SourceId
+	case "sourcePrefLabel":
+		return c.SourcePrefLabel
+	case "sourceType":
+		return c.SourceType
+	case "targetId":
+		return c.TargetId
+	case "targetPrefLabel":
+		return c.TargetPrefLabel
+	case "targetType":
+		return c.TargetType
+	case "count":
+		return strconv.Itoa(c.Count)
+	}
+	return ""
+}
+
+//TrendingWindow is the publication period, as epoch seconds, in which annotations are counted for trending concepts.
+//Annotations published from PreviousSince until Since are counted as the previous window, which is empty when both are equal
+type TrendingWindow struct {
+	PreviousSince int64
+	Since         int64
+	Until         int64
+}
+
+//TrendingConcept is the model for a canonical concept ranked by its annotations in a trending window
+type TrendingConcept struct {
+	Rank          int
+	Uuid          string
+	Id            string
+	PrefLabel     string
+	ApiUrl        string
+	Labels        []string
+	Count         int
+	PreviousCount int
+}
+
+//Growth is the relative change of the count since the previous window. It is not defined without annotations in the previous window
+func (c TrendingConcept) Growth() (float64, bool) {
+	if c.PreviousCount == 0 {
+		return 0, false
+	}
+	return float64(c.Count-c.PreviousCount) / float64(c.PreviousCount), true
+}
+
+func (c TrendingConcept) Value(column string) string {
+	switch column {
+	case "rank":
+		return strconv.Itoa(c.Rank)
+	case "id":
+		return c.Id
+	case "prefLabel":
+		return c.PrefLabel
+	case "apiUrl":
+		return c.ApiUrl
+	case "count":
+		return strconv.Itoa(c.Count)
+	case "previousCount":
+		return strconv.Itoa(c.PreviousCount)
+	case "growth":
+		if growth, ok := c.Growth(); ok {
+			return strconv.FormatFloat(growth, 'f', 4, 64)
+		}
 	}
-	if conceptType == "Person" {
-		stmt = `
-		MATCH (:Content)-[:MENTIONS|MAJOR_MENTIONS|ABOUT|IS_CLASSIFIED_BY|IS_PRIMARILY_CLASSIFIED_BY|HAS_AUTHOR]->(:Concept)-[:EQUIVALENT_TO]->(x:Person)
-		USING SCAN x:Person
-		RETURN DISTINCT x.prefUUID as Uuid, x.prefLabel as PrefLabel, labels(x) as Labels
+	return ""
+}
+
+//predicates maps the annotation relationships to the predicates used by the public APIs
+var predicates = map[string]string{
+	"MENTIONS":                   "mentions",
+	"MAJOR_MENTIONS":             "majorMentions",
+	"ABOUT":                      "about",
+	"IS_CLASSIFIED_BY":           "isClassifiedBy",
+	"IS_PRIMARILY_CLASSIFIED_BY": "isPrimarilyClassifiedBy",
+	"HAS_AUTHOR":                 "hasAuthor",
+	"HAS_BRAND":                  "hasBrand",
+}
+
+//ReadOptions tunes which concepts of a type are returned by Read
+type ReadOptions struct {
+	IncludeUnannotated bool `json:"IncludeUnannotated,omitempty"`
+	MinAnnotations     int  `json:"MinAnnotations,omitempty"`
+	//Limit caps the concepts read, e.g. to preview them. Zero reads every concept
+	Limit int `json:"-"`
+}
+
+func (s *NeoService) Read(conceptType string, opts ReadOptions, conceptCh chan Concept) (int, bool, error) {
+	results := []Concept{}
+	stmt := matchConcepts(conceptType, opts)
+	if opts.Limit > 0 {
+		//the concepts are limited before returnConcepts aggregates their source concepts, so only those returned are looked up
+		stmt += `
+		WITH x, annotated LIMIT {limit}
 		`
 	}
+	stmt += returnConcepts(conceptType)
 
 	query := &neoism.CypherQuery{
 		Statement: stmt,
-		Result:    &results,
+		Parameters: neoism.Props{
+			"minAnnotations": opts.MinAnnotations,
+			"limit":          opts.Limit,
+		},
+		Result: &results,
 	}
 
 	err := s.Connection.CypherBatch([]*neoism.CypherQuery{query})
@@ -92,6 +262,251 @@ func (s *NeoService) Read(conceptType string, conceptCh chan Concept) (int, bool
 	return len(results), true, nil
 }
 
+//Count returns the number of concepts of the given type Read would return, without reading them
+func (s *NeoService) Count(conceptType string, opts ReadOptions) (int, error) {
+	results := []struct {
+		Count int
+	}{}
+	query := &neoism.CypherQuery{
+		Statement: matchConcepts(conceptType, opts) + `
+		RETURN count(x) AS Count
+		`,
+		Parameters: neoism.Props{
+			"minAnnotations": opts.MinAnnotations,
+		},
+		Result: &results,
+	}
+
+	err := s.Connection.CypherBatch([]*neoism.CypherQuery{query})
+	if err != nil || len(results) == 0 {
+		return 0, err
+	}
+	return results[0].Count, nil
+}
+
+//ReadAnnotations returns the annotations of the canonical concepts of the given type by the next limit content annotating them, in the order
+//of their UUIDs after the given one. Pages are read by key instead of skipping the rows of the previous ones, so each page only reads its content
+func (s *NeoService) ReadAnnotations(conceptType, after string, limit int) ([]Annotation, error) {
+	results := []Annotation{}
+	query := &neoism.CypherQuery{
+		Statement: fmt.Sprintf(`
+		MATCH (x:%s)<-[:EQUIVALENT_TO]-(:Concept)<-[:%s]-(content:Content)
+		WHERE content.uuid > {after}
+		WITH DISTINCT content
+		ORDER BY content.uuid
+		LIMIT {limit}
+		MATCH (content)-[rel:%[2]s]->(:Concept)-[:EQUIVALENT_TO]->(x:%[1]s)
+		RETURN DISTINCT content.uuid AS ContentUuid, x.prefUUID AS ConceptUuid, type(rel) AS Predicate
+		ORDER BY ContentUuid, ConceptUuid, Predicate
+		`, conceptType, annotationPredicates(conceptType)),
+		Parameters: neoism.Props{
+			"after": after,
+			"limit": limit,
+		},
+		Result: &results,
+	}
+
+	err := s.Connection.CypherBatch([]*neoism.CypherQuery{query})
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].ConceptId = mapper.IDURL(results[i].ConceptUuid)
+		results[i].ConceptType = conceptType
+		if predicate, ok := predicates[results[i].Predicate]; ok {
+			results[i].Predicate = predicate
+		}
+	}
+	return results, nil
+}
+
+//ReadConcordance returns a page of the sources of the canonical concepts of the given type which are exported with the given options,
+//in the order of their prefUUID and source UUID after the given ones. Pages are read by key, so the concepts of the previous pages aren't matched again
+func (s *NeoService) ReadConcordance(conceptType string, opts ReadOptions, afterPrefUuid, afterSourceUuid string, limit int) ([]Concordance, error) {
+	results := []Concordance{}
+	query := &neoism.CypherQuery{
+		Statement: matchConceptsWhere(conceptType, opts, "x.prefUUID >= {afterPrefUuid}") + `
+		MATCH (x)<-[:EQUIVALENT_TO]-(source)
+		WHERE x.prefUUID > {afterPrefUuid} OR source.uuid > {afterSourceUuid}
+		RETURN x.prefUUID AS PrefUuid, source.uuid AS SourceUuid, source.authority AS Authority, source.authorityValue AS AuthorityValue
+		ORDER BY PrefUuid, SourceUuid
+		LIMIT {limit}
+		`,
+		Parameters: neoism.Props{
+			"minAnnotations":  opts.MinAnnotations,
+			"afterPrefUuid":   afterPrefUuid,
+			"afterSourceUuid": afterSourceUuid,
+			"limit":           limit,
+		},
+		Result: &results,
+	}
+
+	err := s.Connection.CypherBatch([]*neoism.CypherQuery{query})
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].ConceptId = mapper.IDURL(results[i].PrefUuid)
+		results[i].ConceptType = conceptType
+	}
+	return results, nil
+}
+
+//ReadCoOccurrences returns a page of the pairs of canonical concepts of the given types annotated by at least minCount same content,
+//the source being of sourceType. Every pair is returned once, with the source having the lower prefUUID and not being of a type listed before sourceType.
+//The pairs follow the given source and target UUIDs, and the sources of the previous pages are left out before the pairs are counted
+func (s *NeoService) ReadCoOccurrences(sourceType string, conceptTypes []string, minCount int, afterSourceUuid, afterTargetUuid string, limit int) ([]CoOccurrence, error) {
+	var previousTypes []string
+	for _, cType := range conceptTypes {
+		if cType == sourceType {
+			break
+		}
+		previousTypes = append(previousTypes, cType)
+	}
+	results := []CoOccurrence{}
+	query := &neoism.CypherQuery{
+		Statement: fmt.Sprintf(`
+		MATCH (x:%s)<-[:EQUIVALENT_TO]-(:Concept)<-[:%s]-(content:Content)-[:%[2]s]->(:Concept)-[:EQUIVALENT_TO]->(y:Concept)
+		WHERE x.prefUUID >= {afterSourceUuid} AND x.prefUUID < y.prefUUID
+			AND (x.prefUUID > {afterSourceUuid} OR y.prefUUID > {afterTargetUuid})
+			AND none(l IN labels(x) WHERE l IN {previousTypes})
+			AND any(l IN labels(y) WHERE l IN {conceptTypes})
+		WITH x, y, count(DISTINCT content) AS count
+		WHERE count >= {minCount}
+		RETURN x.prefUUID AS SourceUuid, x.prefLabel AS SourcePrefLabel, {sourceType} AS SourceType,
+			y.prefUUID AS TargetUuid, y.prefLabel AS TargetPrefLabel, head([l IN {conceptTypes} WHERE l IN labels(y)]) AS TargetType,
+			count AS Count
+		ORDER BY SourceUuid, TargetUuid
+		LIMIT {limit}
+		`, sourceType, conceptPredicates),
+		Parameters: neoism.Props{
+			"sourceType":      sourceType,
+			"previousTypes":   previousTypes,
+			"conceptTypes":    conceptTypes,
+			"minCount":        minCount,
+			"afterSourceUuid": afterSourceUuid,
+			"afterTargetUuid": afterTargetUuid,
+			"limit":           limit,
+		},
+		Result: &results,
+	}
+
+	err := s.Connection.CypherBatch([]*neoism.CypherQuery{query})
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].SourceId = mapper.IDURL(results[i].SourceUuid)
+		results[i].TargetId = mapper.IDURL(results[i].TargetUuid)
+	}
+	return results, nil
+}
+
+//ReadTrending returns a page of the canonical concepts of the given type annotated by content published in the window, ranked by their number of annotating content
+func (s *NeoService) ReadTrending(conceptType string, window TrendingWindow, skip, limit int) ([]TrendingConcept, error) {
+	results := []TrendingConcept{}
+	query := &neoism.CypherQuery{
+		Statement: fmt.Sprintf(`
+		MATCH (x:%s)<-[:EQUIVALENT_TO]-(:Concept)<-[:%s]-(content:Content)
+		WHERE content.publishedDateEpoch >= {previousSince} AND content.publishedDateEpoch < {until}
+		WITH x, count(DISTINCT CASE WHEN content.publishedDateEpoch >= {since} THEN content END) AS count,
+			count(DISTINCT CASE WHEN content.publishedDateEpoch < {since} THEN content END) AS previousCount
+		WHERE count > 0
+		RETURN x.prefUUID AS Uuid, x.prefLabel AS PrefLabel, labels(x) AS Labels, count AS Count, previousCount AS PreviousCount
+		ORDER BY Count DESC, Uuid
+		SKIP {skip} LIMIT {limit}
+		`, conceptType, annotationPredicates(conceptType)),
+		Parameters: neoism.Props{
+			"previousSince": window.PreviousSince,
+			"since":         window.Since,
+			"until":         window.Until,
+			"skip":          skip,
+			"limit":         limit,
+		},
+		Result: &results,
+	}
+
+	err := s.Connection.CypherBatch([]*neoism.CypherQuery{query})
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Rank = skip + i + 1
+		results[i].ApiUrl = mapper.APIURL(results[i].Uuid, results[i].Labels, "")
+		results[i].Id = mapper.IDURL(results[i].Uuid)
+	}
+	return results, nil
+}
+
+const conceptPredicates = "MENTIONS|MAJOR_MENTIONS|ABOUT|IS_CLASSIFIED_BY|IS_PRIMARILY_CLASSIFIED_BY|HAS_AUTHOR"
+
+func annotationPredicates(conceptType string) string {
+	if conceptType == "Organisation" || conceptType == "Person" {
+		return conceptPredicates
+	}
+	return conceptPredicates + "|HAS_BRAND"
+}
+
+//matchConcepts returns the canonical concepts of the given type as x, with annotated telling whether any content annotates them
+func matchConcepts(conceptType string, opts ReadOptions) string {
+	return matchConceptsWhere(conceptType, opts, "")
+}
+
+//matchConceptsWhere returns the canonical concepts of the given type matching the condition on x, so it applies before their annotations are counted
+func matchConceptsWhere(conceptType string, opts ReadOptions, condition string) string {
+	if opts.IncludeUnannotated {
+		where := "exists(x.prefUUID)"
+		if condition != "" {
+			where += " AND " + condition
+		}
+		return fmt.Sprintf(`
+		MATCH (x:%s)
+		WHERE %s
+		OPTIONAL MATCH (x)<-[:EQUIVALENT_TO]-(:Concept)<-[:%s]-(content:Content)
+		WITH x, count(DISTINCT content) AS annotations
+		WHERE annotations >= {minAnnotations}
+		WITH x, annotations > 0 AS annotated
+		`, conceptType, where, annotationPredicates(conceptType))
+	}
+	where := ""
+	if condition != "" {
+		where = "WHERE " + condition
+	}
+	if opts.MinAnnotations > 0 {
+		return fmt.Sprintf(`
+		MATCH (x:%s)<-[:EQUIVALENT_TO]-(:Concept)<-[:%s]-(content:Content)
+		USING SCAN x:%[1]s
+		%s
+		WITH x, count(DISTINCT content) AS annotations
+		WHERE annotations >= {minAnnotations}
+		WITH x, true AS annotated
+		`, conceptType, annotationPredicates(conceptType), where)
+	}
+	return fmt.Sprintf(`
+		MATCH (x:%s)<-[:EQUIVALENT_TO]-(:Concept)<-[:%s]-(:Content)
+		USING SCAN x:%[1]s
+		%s
+		WITH DISTINCT x, true AS annotated
+		`, conceptType, annotationPredicates(conceptType), where)
+}
+
+func returnConcepts(conceptType string) string {
+	if conceptType == "Organisation" {
+		return `
+		MATCH (x)<-[:EQUIVALENT_TO]-(concept)
+		OPTIONAL MATCH (concept)<-[:ISSUED_BY]-(fi:FinancialInstrument)
+		WITH x, annotated, collect(DISTINCT CASE concept.authority WHEN 'FACTSET' THEN concept.authorityValue END) AS factsetIds,
+			collect(DISTINCT fi.figiCode) as figiCodes
+		RETURN x.prefUUID AS Uuid, labels(x) AS Labels, x.prefLabel AS PrefLabel, x.leiCode AS leiCode,
+			reduce(s=head(factsetIds), n IN tail(factsetIds) | s + ';' + n) AS factsetId,
+			reduce(s=head(figiCodes), n IN tail(figiCodes) | s + ';' + n) AS FIGI, annotated AS Annotated
+		`
+	}
+	return `
+		RETURN x.prefUUID AS Uuid, x.prefLabel AS PrefLabel, labels(x) AS Labels, annotated AS Annotated
+		`
+}
+
 func (s *NeoService) CheckConnectivity(conn neoutils.NeoConnection) (string, error) {
 	err := neoutils.Check(conn)
 	if err != nil {
@@ -65,7 +65,7 @@ func TestNeoService_ReadBrand(t *testing.T) {
 	neoSvc := NewNeoService(conn, "not-needed")
 
 	conceptCh := make(chan Concept)
-	count, found, err := neoSvc.Read("Brand", conceptCh)
+	count, found, err := neoSvc.Read("Brand", ReadOptions{}, conceptCh)
 
 	assert.NoError(t, err, "Error reading from Neo")
 	assert.True(t, found)
@@ -90,6 +90,145 @@ waitLoop:
 	}
 }
 
+func TestNeoService_CountBrand(t *testing.T) {
+	conn := getDatabaseConnection(t)
+	svc := concepts.NewConceptService(conn)
+	assert.NoError(t, svc.Initialise())
+
+	cleanDB(t, conn)
+	writeBrands(t, &svc)
+	writeContent(t, conn)
+	writeAnnotation(t, conn, fmt.Sprintf("./fixtures/Annotations-%s.json", contentUUID), "v1")
+
+	neoSvc := NewNeoService(conn, "not-needed")
+
+	count, err := neoSvc.Count("Brand", ReadOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = neoSvc.Count("Brand", ReadOptions{MinAnnotations: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestNeoService_ReadIncludeUnannotated(t *testing.T) {
+	conn := getDatabaseConnection(t)
+	svc := concepts.NewConceptService(conn)
+	assert.NoError(t, svc.Initialise())
+
+	tests := []struct {
+		name              string
+		conceptType       string
+		fixture           string
+		uuid              string
+		annotate          bool
+		expectedAnnotated bool
+	}{
+		{
+			name:              "Unannotated Person",
+			conceptType:       "Person",
+			fixture:           fmt.Sprintf("./fixtures/Person-%s.json", personUUID),
+			uuid:              personUUID,
+			expectedAnnotated: false,
+		},
+		{
+			name:              "Annotated Person",
+			conceptType:       "Person",
+			fixture:           fmt.Sprintf("./fixtures/Person-%s.json", personUUID),
+			uuid:              personUUID,
+			annotate:          true,
+			expectedAnnotated: true,
+		},
+		{
+			name:              "Unannotated Organisation",
+			conceptType:       "Organisation",
+			fixture:           fmt.Sprintf("./fixtures/Organisation-Fakebook-%s-Factset.json", companyUUID),
+			uuid:              companyUUID,
+			expectedAnnotated: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cleanDB(t, conn)
+			writeJSONToConceptService(t, &svc, test.fixture)
+			if test.annotate {
+				writeContent(t, conn)
+				writeAnnotation(t, conn, fmt.Sprintf("./fixtures/Annotations-%s-person.json", contentUUID), "pac")
+			}
+			neoSvc := NewNeoService(conn, "not-needed")
+
+			conceptCh := make(chan Concept)
+			count, found, err := neoSvc.Read(test.conceptType, ReadOptions{IncludeUnannotated: true}, conceptCh)
+
+			assert.NoError(t, err, "Error reading from Neo")
+			assert.True(t, found)
+			assert.Equal(t, 1, count)
+		waitLoop:
+			for {
+				select {
+				case c, open := <-conceptCh:
+					if !open {
+						break waitLoop
+					}
+					assert.Equal(t, test.uuid, c.Uuid)
+					assert.Equal(t, test.expectedAnnotated, c.Annotated)
+				case <-time.After(3 * time.Second):
+					t.FailNow()
+				}
+			}
+		})
+	}
+}
+
+func TestNeoService_ReadMinAnnotations(t *testing.T) {
+	conn := getDatabaseConnection(t)
+	svc := concepts.NewConceptService(conn)
+	assert.NoError(t, svc.Initialise())
+
+	tests := []struct {
+		name          string
+		opts          ReadOptions
+		expectedCount int
+	}{
+		{
+			name:          "Threshold reached",
+			opts:          ReadOptions{MinAnnotations: 1},
+			expectedCount: 1,
+		},
+		{
+			name:          "Threshold not reached",
+			opts:          ReadOptions{MinAnnotations: 2},
+			expectedCount: 0,
+		},
+		{
+			name:          "Threshold not reached including unannotated",
+			opts:          ReadOptions{MinAnnotations: 2, IncludeUnannotated: true},
+			expectedCount: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cleanDB(t, conn)
+			writeJSONToConceptService(t, &svc, fmt.Sprintf("./fixtures/Person-%s.json", personUUID))
+			writeContent(t, conn)
+			writeAnnotation(t, conn, fmt.Sprintf("./fixtures/Annotations-%s-person.json", contentUUID), "pac")
+			neoSvc := NewNeoService(conn, "not-needed")
+
+			conceptCh := make(chan Concept)
+			count, found, err := neoSvc.Read("Person", test.opts, conceptCh)
+
+			assert.NoError(t, err, "Error reading from Neo")
+			assert.Equal(t, test.expectedCount, count)
+			assert.Equal(t, test.expectedCount != 0, found)
+			for c := range conceptCh {
+				assert.Equal(t, personUUID, c.Uuid)
+			}
+		})
+	}
+}
+
 func TestNeoService_DoNotReadBrokenConcepts(t *testing.T) {
 	conn := getDatabaseConnection(t)
 	svc := concepts.NewConceptService(conn)
@@ -153,7 +292,7 @@ func TestNeoService_DoNotReadBrokenConcepts(t *testing.T) {
 			neoSvc := NewNeoService(conn, "not-needed")
 
 			conceptCh := make(chan Concept)
-			count, found, err := neoSvc.Read(test.conceptType, conceptCh)
+			count, found, err := neoSvc.Read(test.conceptType, ReadOptions{}, conceptCh)
 
 			assert.NoError(t, err, "Error reading from Neo")
 			assert.False(t, found)
@@ -175,7 +314,7 @@ func TestNeoService_ReadHasBrand(t *testing.T) {
 	neoSvc := NewNeoService(conn, "not-needed")
 
 	conceptCh := make(chan Concept)
-	count, found, err := neoSvc.Read("Brand", conceptCh)
+	count, found, err := neoSvc.Read("Brand", ReadOptions{}, conceptCh)
 
 	assert.NoError(t, err, "Error reading from Neo")
 	assert.True(t, found)
@@ -238,7 +377,7 @@ func TestNeoService_ReadOrganisation(t *testing.T) {
 			neoSvc := NewNeoService(conn, "not-needed")
 
 			conceptCh := make(chan Concept)
-			count, found, err := neoSvc.Read("Organisation", conceptCh)
+			count, found, err := neoSvc.Read("Organisation", ReadOptions{}, conceptCh)
 
 			assert.NoError(t, err, "Error reading from Neo")
 			assert.True(t, found)
@@ -317,7 +456,7 @@ func TestNeoService_ReadPerson(t *testing.T) {
 			neoSvc := NewNeoService(conn, "not-needed")
 
 			conceptCh := make(chan Concept)
-			count, found, err := neoSvc.Read(test.readAs, conceptCh)
+			count, found, err := neoSvc.Read(test.readAs, ReadOptions{}, conceptCh)
 
 			assert.NoError(t, err, "Error reading from Neo")
 			assert.Equal(t, test.expectedCount, count)
@@ -346,13 +485,157 @@ func TestNeoService_ReadPerson(t *testing.T) {
 	}
 }
 
+func TestNeoService_ReadAnnotations(t *testing.T) {
+	conn := getDatabaseConnection(t)
+	svc := concepts.NewConceptService(conn)
+	assert.NoError(t, svc.Initialise())
+
+	cleanDB(t, conn)
+	writeJSONToConceptService(t, &svc, fmt.Sprintf("./fixtures/Person-%s.json", personUUID))
+	writeContent(t, conn)
+	writeAnnotation(t, conn, fmt.Sprintf("./fixtures/Annotations-%s-person.json", contentUUID), "pac")
+	neoSvc := NewNeoService(conn, "not-needed")
+
+	annotations, err := neoSvc.ReadAnnotations("Person", "", 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	require.Equal(t, 1, len(annotations))
+	assert.Equal(t, contentUUID, annotations[0].ContentUuid)
+	assert.Equal(t, personUUID, annotations[0].ConceptUuid)
+	assert.Equal(t, "http://api.ft.com/things/"+personUUID, annotations[0].ConceptId)
+	assert.Equal(t, "Person", annotations[0].ConceptType)
+	assert.NotEmpty(t, annotations[0].Predicate)
+
+	annotations, err = neoSvc.ReadAnnotations("Person", contentUUID, 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	assert.Empty(t, annotations)
+}
+
+func TestNeoService_ReadConcordance(t *testing.T) {
+	conn := getDatabaseConnection(t)
+	svc := concepts.NewConceptService(conn)
+	assert.NoError(t, svc.Initialise())
+
+	cleanDB(t, conn)
+	writeJSONToConceptService(t, &svc, fmt.Sprintf("./fixtures/Organisation-Fakebook-%s-Factset.json", companyUUID))
+	writeContent(t, conn)
+	writeAnnotation(t, conn, fmt.Sprintf("./fixtures/Annotations-%s-org.json", contentUUID), "v2")
+	neoSvc := NewNeoService(conn, "not-needed")
+
+	concordances, err := neoSvc.ReadConcordance("Organisation", ReadOptions{}, "", "", 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	require.Equal(t, 2, len(concordances))
+	authorities := map[string]string{}
+	for _, c := range concordances {
+		assert.Equal(t, companyUUID, c.PrefUuid)
+		assert.Equal(t, "http://api.ft.com/things/"+companyUUID, c.ConceptId)
+		assert.Equal(t, "Organisation", c.ConceptType)
+		authorities[c.Authority] = c.AuthorityValue
+	}
+	assert.Equal(t, map[string]string{"Smartlogic": companyUUID, "FACTSET": "FACTSET1"}, authorities)
+
+	page, err := neoSvc.ReadConcordance("Organisation", ReadOptions{}, "", "", 1)
+	assert.NoError(t, err, "Error reading from Neo")
+	require.Equal(t, 1, len(page))
+	next, err := neoSvc.ReadConcordance("Organisation", ReadOptions{}, page[0].PrefUuid, page[0].SourceUuid, 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	require.Equal(t, 1, len(next))
+	assert.Equal(t, concordances[1].SourceUuid, next[0].SourceUuid)
+
+	concordances, err = neoSvc.ReadConcordance("Organisation", ReadOptions{MinAnnotations: 2}, "", "", 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	assert.Empty(t, concordances)
+}
+
+func TestNeoService_ReadCoOccurrences(t *testing.T) {
+	conn := getDatabaseConnection(t)
+	svc := concepts.NewConceptService(conn)
+	assert.NoError(t, svc.Initialise())
+
+	cleanDB(t, conn)
+	writeJSONToConceptService(t, &svc, fmt.Sprintf("./fixtures/Organisation-Fakebook-%s.json", companyUUID))
+	writeJSONToConceptService(t, &svc, fmt.Sprintf("./fixtures/Person-%s.json", personUUID))
+	writeContent(t, conn)
+	writeAnnotation(t, conn, fmt.Sprintf("./fixtures/Annotations-%s-org.json", contentUUID), "v2")
+	writeAnnotation(t, conn, fmt.Sprintf("./fixtures/Annotations-%s-person.json", contentUUID), "pac")
+	neoSvc := NewNeoService(conn, "not-needed")
+
+	coOccurrences, err := neoSvc.ReadCoOccurrences("Organisation", []string{"Organisation", "Person"}, 1, "", "", 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	assert.Empty(t, coOccurrences, "the pair has the person as its source")
+
+	coOccurrences, err = neoSvc.ReadCoOccurrences("Person", []string{"Organisation", "Person"}, 1, "", "", 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	require.Equal(t, 1, len(coOccurrences))
+	assert.Equal(t, personUUID, coOccurrences[0].SourceUuid)
+	assert.Equal(t, "http://api.ft.com/things/"+personUUID, coOccurrences[0].SourceId)
+	assert.Equal(t, "Person", coOccurrences[0].SourceType)
+	assert.Equal(t, companyUUID, coOccurrences[0].TargetUuid)
+	assert.Equal(t, "Organisation", coOccurrences[0].TargetType)
+	assert.Equal(t, 1, coOccurrences[0].Count)
+
+	next, err := neoSvc.ReadCoOccurrences("Person", []string{"Organisation", "Person"}, 1, coOccurrences[0].SourceUuid, coOccurrences[0].TargetUuid, 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	assert.Empty(t, next, "the pairs of the previous pages aren't read again")
+
+	coOccurrences, err = neoSvc.ReadCoOccurrences("Person", []string{"Organisation", "Person"}, 2, "", "", 10)
+	assert.NoError(t, err, "Error reading from Neo")
+	assert.Empty(t, coOccurrences)
+}
+
+func TestNeoService_ReadTrending(t *testing.T) {
+	conn := getDatabaseConnection(t)
+	svc := concepts.NewConceptService(conn)
+	assert.NoError(t, svc.Initialise())
+
+	cleanDB(t, conn)
+	writeJSONToConceptService(t, &svc, fmt.Sprintf("./fixtures/Person-%s.json", personUUID))
+	writeContent(t, conn)
+	writeAnnotation(t, conn, fmt.Sprintf("./fixtures/Annotations-%s-person.json", contentUUID), "pac")
+	neoSvc := NewNeoService(conn, "not-needed")
+
+	published := time.Date(2016, 12, 15, 19, 18, 1, 0, time.UTC)
+	tests := []struct {
+		name                  string
+		window                TrendingWindow
+		expectedCount         int
+		expectedPreviousCount int
+	}{
+		{
+			name:          "Published in the window",
+			window:        TrendingWindow{PreviousSince: published.AddDate(0, 0, -1).Unix(), Since: published.AddDate(0, 0, -1).Unix(), Until: published.AddDate(0, 0, 1).Unix()},
+			expectedCount: 1,
+		},
+		{
+			name:   "Published in the previous window only",
+			window: TrendingWindow{PreviousSince: published.AddDate(0, 0, -1).Unix(), Since: published.AddDate(0, 0, 1).Unix(), Until: published.AddDate(0, 0, 2).Unix()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trending, err := neoSvc.ReadTrending("Person", test.window, 0, 10)
+			assert.NoError(t, err, "Error reading from Neo")
+			if test.expectedCount == 0 {
+				assert.Empty(t, trending)
+				return
+			}
+			require.Equal(t, 1, len(trending))
+			assert.Equal(t, 1, trending[0].Rank)
+			assert.Equal(t, personUUID, trending[0].Uuid)
+			assert.Equal(t, "http://api.ft.com/people/"+personUUID, trending[0].ApiUrl)
+			assert.Equal(t, test.expectedCount, trending[0].Count)
+			assert.Equal(t, test.expectedPreviousCount, trending[0].PreviousCount)
+		})
+	}
+}
+
 func TestNeoService_ReadWithoutResult(t *testing.T) {
 	conn := getDatabaseConnection(t)
 	cleanDB(t, conn)
 	neoSvc := NewNeoService(conn, "not-needed")
 
 	conceptCh := make(chan Concept)
-	count, found, err := neoSvc.Read("Brand", conceptCh)
+	count, found, err := neoSvc.Read("Brand", ReadOptions{}, conceptCh)
 
 	assert.NoError(t, err, "Error reading from Neo")
 	assert.False(t, found)
@@ -396,7 +679,7 @@ func TestNeoService_ReadWithError(t *testing.T) {
 	neoSvc := NewNeoService(conn, "not-needed")
 
 	conceptCh := make(chan Concept)
-	count, found, err := neoSvc.Read("Brand", conceptCh)
+	count, found, err := neoSvc.Read("Brand", ReadOptions{}, conceptCh)
 
 	assert.Error(t, err, "Error reading from Neo")
 	assert.Equal(t, "BOOM!", err.Error())
@@ -0,0 +1,55 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+type Type string
+
+const (
+	JobCreated     Type = "JobCreated"
+	WorkerStarted  Type = "WorkerStarted"
+	WorkerFinished Type = "WorkerFinished"
+	WorkerFailed   Type = "WorkerFailed"
+	JobFinished    Type = "JobFinished"
+)
+
+//Event is a state change of a job or of one of its workers
+type Event struct {
+	Type          Type      `json:"Type"`
+	JobID         string    `json:"JobID"`
+	Time          time.Time `json:"Time"`
+	TransactionID string    `json:"TransactionID,omitempty"`
+	ConceptType   string    `json:"ConceptType,omitempty"`
+	Concepts      []string  `json:"Concepts,omitempty"`
+	Count         int       `json:"Count,omitempty"`
+	Key           string    `json:"Key,omitempty"`
+	Keys          []string  `json:"Keys,omitempty"`
+	Failed        []string  `json:"Failed,omitempty"`
+	Published     bool      `json:"Published,omitempty"`
+	ErrorMessage  string    `json:"ErrorMessage,omitempty"`
+}
+
+type Publisher interface {
+	Publish(e Event) error
+}
+
+//MemoryPublisher keeps the published events in memory
+type MemoryPublisher struct {
+	sync.RWMutex
+	events []Event
+}
+
+func (p *MemoryPublisher) Publish(e Event) error {
+	p.Lock()
+	defer p.Unlock()
+	p.events = append(p.events, e)
+	return nil
+}
+
+func (p *MemoryPublisher) Events() []Event {
+	p.RLock()
+	defer p.RUnlock()
+	return append([]Event{}, p.events...)
+}
@@ -0,0 +1,123 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/monitoring"
+	logger "github.com/Financial-Times/go-logger/v2"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	kafkaBatchTimeout = 10 * time.Millisecond
+	kafkaWriteTimeout = 10 * time.Second
+	//kafkaBufferSize is how many events wait to be written before new ones are dropped
+	kafkaBufferSize = 1000
+	//kafkaBatchSize is how many waiting events are written at once
+	kafkaBatchSize = 100
+)
+
+var (
+	ErrBufferFull = errors.New("the buffer of the events to publish is full")
+	ErrClosed     = errors.New("the publisher is closed")
+)
+
+//KafkaPublisher writes the events as JSON messages keyed by job ID to a Kafka topic, so the events of a job keep their order.
+//The events are written in the background from a bounded buffer, so an unavailable broker holds back neither the requests nor the jobs
+type KafkaPublisher struct {
+	Writer *kafka.Writer
+	Log    *logger.UPPLogger
+
+	sync.RWMutex
+	messages chan kafka.Message
+	done     chan struct{}
+	closed   bool
+}
+
+func NewKafkaPublisher(brokers []string, topic string, log *logger.UPPLogger) *KafkaPublisher {
+	p := &KafkaPublisher{
+		Writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: kafkaBatchTimeout,
+			WriteTimeout: kafkaWriteTimeout,
+		},
+		Log:      log,
+		messages: make(chan kafka.Message, kafkaBufferSize),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+//Publish buffers the event to be written, dropping it when the buffer is full
+func (p *KafkaPublisher) Publish(e Event) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := kafka.Message{
+		Key:   []byte(e.JobID),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "Message-Type", Value: []byte(e.Type)},
+			{Key: "X-Request-Id", Value: []byte(e.TransactionID)},
+		},
+	}
+	p.RLock()
+	defer p.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	select {
+	case p.messages <- msg:
+		return nil
+	default:
+		monitoring.EventsDropped.WithLabelValues("buffer_full").Inc()
+		return ErrBufferFull
+	}
+}
+
+//run writes the buffered events in batches until the publisher is closed
+func (p *KafkaPublisher) run() {
+	defer close(p.done)
+	for msg := range p.messages {
+		batch := []kafka.Message{msg}
+	drain:
+		for len(batch) < kafkaBatchSize {
+			select {
+			case next, ok := <-p.messages:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), kafkaWriteTimeout)
+		err := p.Writer.WriteMessages(ctx, batch...)
+		cancel()
+		if err != nil {
+			monitoring.EventsDropped.WithLabelValues("write_failed").Add(float64(len(batch)))
+			p.Log.WithError(err).Warnf("Failed to publish %d job events", len(batch))
+		}
+	}
+}
+
+//Close writes the buffered events and closes the writer
+func (p *KafkaPublisher) Close() error {
+	p.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.messages)
+	}
+	p.Unlock()
+	<-p.done
+	return p.Writer.Close()
+}
@@ -0,0 +1,57 @@
+// +build integration
+
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/pborman/uuid"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func getKafkaAddress(t *testing.T) string {
+	if testing.Short() {
+		t.Skip("Kafka integration for long tests only.")
+	}
+	address := os.Getenv("KAFKA_TEST_ADDRESS")
+	if address == "" {
+		address = "localhost:9092"
+	}
+	return address
+}
+
+func TestKafkaPublisher_Publish(t *testing.T) {
+	address := getKafkaAddress(t)
+	topic := "ConceptExportEventsTest"
+	conn, err := kafka.DialLeader(context.Background(), "tcp", address, topic, 0)
+	assert.NoError(t, err, "Failed to connect to Kafka")
+	defer conn.Close()
+	offset, err := conn.ReadLastOffset()
+	assert.NoError(t, err)
+
+	publisher := NewKafkaPublisher([]string{address}, topic, logger.NewUPPLogger("Test", "PANIC"))
+	defer publisher.Close()
+	e := Event{Type: WorkerFinished, JobID: "job_" + uuid.New(), Time: time.Now().UTC(), TransactionID: "tid_1234", ConceptType: "Brand", Count: 3, Key: "Brand.csv"}
+	assert.NoError(t, publisher.Publish(e))
+
+	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: []string{address}, Topic: topic, Partition: 0})
+	defer reader.Close()
+	assert.NoError(t, reader.SetOffset(offset))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	msg, err := reader.ReadMessage(ctx)
+	assert.NoError(t, err)
+
+	assert.Equal(t, e.JobID, string(msg.Key))
+	var read Event
+	assert.NoError(t, json.Unmarshal(msg.Value, &read))
+	assert.True(t, e.Time.Equal(read.Time))
+	read.Time = e.Time
+	assert.Equal(t, e, read)
+}
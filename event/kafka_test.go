@@ -0,0 +1,19 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaPublisher_PublishDropsWhenBufferIsFull(t *testing.T) {
+	publisher := &KafkaPublisher{Log: logger.NewUPPLogger("Test", "PANIC"), messages: make(chan kafka.Message, 1)}
+
+	assert.NoError(t, publisher.Publish(Event{Type: JobCreated, JobID: "job_1"}))
+	assert.Equal(t, ErrBufferFull, publisher.Publish(Event{Type: JobFinished, JobID: "job_1"}))
+	msg := <-publisher.messages
+	assert.Equal(t, "job_1", string(msg.Key))
+	assert.NoError(t, publisher.Publish(Event{Type: JobFinished, JobID: "job_1"}))
+}
@@ -12,6 +12,29 @@ if [ -z "${AUTH}" ]; then
   echo ">>Authentication is empty but is mandatory in the form of \"Basic xxx\""
   exit 1
 fi
+apiKeyHeader=()
+if [ -n "${API_KEY}" ]; then
+  apiKeyHeader=(-H "X-Api-Key: ${API_KEY}")
+fi
+retries=${RETRIES:-20}
+#call sets body and code to the response of the request. A replica which isn't the leader rejects exports with 503,
+#and may not know a job created by the leader before its next renewal of the lease, so these are retried
+call() {
+  for ((attempt = 1; attempt <= retries; attempt++)); do
+    response=`curl -qSs -w "\n%{http_code}" "$@" -H "Authorization: ${AUTH}" "${apiKeyHeader[@]}" 2>/dev/null`
+    if [ "$?" -ne 0 ]; then
+      code=0
+      return
+    fi
+    code=`echo "${response}" | tail -n1`
+    body=`echo "${response}" | sed '$d'`
+    if [ "${code}" != "503" ] && [ "${code}" != "404" ]; then
+      return
+    fi
+    echo "Got ${code}, retrying in 3 seconds (${attempt}/${retries})"
+    sleep 3
+  done
+}
 postBody=""
 if [ -n "${CONCEPT_TYPES}" ]; then
   echo "Export will be made for the following concept types: ${CONCEPT_TYPES}"
@@ -19,25 +42,40 @@ if [ -n "${CONCEPT_TYPES}" ]; then
 else
   echo "FULL concept export initiated."
 fi
-jobResult=`curl -qSfs "${EXPORTER_URL}/export" -H "Authorization: ${AUTH}" -XPOST -d "${postBody}" 2>/dev/null`
-if [ "$?" -ne 0 ]; then
-  echo ">>Exporter service cannot be called successfully. Maybe service is down or the authentication is incorrect or there is already a running export job? Or no valid candidate concept types in the request?"
+call "${EXPORTER_URL}/export" -XPOST -d "${postBody}"
+if [ "${code}" != "202" ]; then
+  echo ">>Exporter service cannot be called successfully (${code}). Maybe service is down or the authentication is incorrect or the job queue is full? Or no valid candidate concept types in the request? ${body}"
   exit 1
 else
-  jobID=`echo "${jobResult}" | jq '.ID' | cut -d'"' -f2 2>/dev/null`
+  jobResult=${body}
+  jobID=`echo "${jobResult}" | jq -r '.ID' 2>/dev/null`
+  if [ -z "${jobID}" ] || [ "${jobID}" == "null" ]; then
+    echo ">>Exporter service did not return the id of the export job: ${jobResult}"
+    exit 1
+  fi
   echo "Export triggered. Job id: ${jobID}. Checking continuously the status to be in 'Finished'..."
   sleep 3
-  status="Running"
+  status="Queued"
   while [ ${status} != "Finished" ]; do
-  job=`curl -qSfs "${EXPORTER_URL}/job" -H "Authorization: ${AUTH}" 2>/dev/null`
+  call "${EXPORTER_URL}/jobs/${jobID}"
 
-  if [ "$?" -ne 0 ]; then
-	echo ">>Failed to retrieve job"
+  if [ "${code}" != "200" ]; then
+	echo ">>Failed to retrieve job ${jobID} (${code})"
 	exit 1
   else
-	 status=`echo ${job} | jq '.Status' | cut -d'"' -f2 2>/dev/null`
+	 job=${body}
+	 status=`echo ${job} | jq -r '.Status' 2>/dev/null`
   fi
 
+  case "${status}" in
+	Queued|Starting|Running|Finished)
+	  ;;
+	*)
+	  echo ">>Unexpected status of job ${jobID}: ${job}"
+	  exit 1
+	  ;;
+  esac
+
   echo ${job}
   sleep 3
   done
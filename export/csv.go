@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/csv"
 
+	"github.com/Financial-Times/concept-exporter/concept"
 	"github.com/Financial-Times/concept-exporter/db"
 )
 
@@ -14,6 +15,7 @@ type CsvExporter struct {
 type ConceptWriter struct {
 	Buffer *bytes.Buffer
 	Writer *csv.Writer
+	Header []string
 }
 
 func NewCsvExporter() *CsvExporter {
@@ -25,41 +27,43 @@ func (e *CsvExporter) GetBytes(conceptType string) []byte {
 	return e.Writer[conceptType].Buffer.Bytes()
 }
 
-func (e *CsvExporter) Prepare(conceptTypes []string) error {
-	writer := make(map[string]*ConceptWriter, len(conceptTypes))
-	for _, cType := range conceptTypes {
-		buffer := new(bytes.Buffer)
-		writer[cType] = &ConceptWriter{Buffer: buffer, Writer: csv.NewWriter(buffer)}
-		err := writer[cType].Writer.Write(getHeader(cType))
-		if err != nil {
+func (e *CsvExporter) Prepare(conceptTypes []string, opts concept.Options) error {
+	exportTypes := opts.ExportTypes(conceptTypes)
+	e.Writer = make(map[string]*ConceptWriter, len(exportTypes))
+	for _, eType := range exportTypes {
+		if err := e.Reset(eType, opts); err != nil {
 			return err
 		}
 	}
-	e.Writer = writer
 	return nil
 }
 
-func (e *CsvExporter) Write(c db.Concept, conceptType, tid string) error {
-	var rec []string
-	rec = append(rec, c.Id)
-	rec = append(rec, c.PrefLabel)
-	rec = append(rec, c.ApiUrl)
-	if conceptType == "Organisation" {
-		rec = append(rec, c.LeiCode)
-		rec = append(rec, c.FactsetId)
-		rec = append(rec, c.FIGI)
+//Reset discards what was written for the export type, e.g. before it is exported again
+func (e *CsvExporter) Reset(exportType string, opts concept.Options) error {
+	buffer := new(bytes.Buffer)
+	w := &ConceptWriter{Buffer: buffer, Writer: csv.NewWriter(buffer), Header: getHeader(exportType, opts)}
+	e.Writer[exportType] = w
+	return w.Writer.Write(w.Header)
+}
+
+func (e *CsvExporter) Write(r db.Record, exportType, tid string) error {
+	w := e.Writer[exportType]
+	rec := make([]string, len(w.Header))
+	for i, column := range w.Header {
+		rec[i] = r.Value(column)
 	}
 
-	return e.Writer[conceptType].Writer.Write(rec)
+	return w.Writer.Write(rec)
 }
 
-func (e *CsvExporter) GetFileName(conceptType string) string {
-	return conceptType + ".csv"
+func (e *CsvExporter) Format() string {
+	return "csv"
 }
 
-func getHeader(conceptType string) []string {
-	if conceptType == "Organisation" {
-		return []string{"id", "prefLabel", "apiUrl", "leiCode", "factsetId", "FIGI"}
-	}
-	return []string{"id", "prefLabel", "apiUrl"}
+func (e *CsvExporter) MediaType() string {
+	return "text/csv"
+}
+
+func (e *CsvExporter) GetFileName(exportType string) string {
+	return exportType + ".csv"
 }
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+)
+
+const ManifestFileName = "manifest.json"
+
+//File describes an exported file uploaded by a job
+type File struct {
+	Name       string `json:"Name"`
+	Key        string `json:"Key"`
+	ExportType string `json:"ExportType"`
+	Rows       int    `json:"Rows"`
+	Bytes      int    `json:"Bytes"`
+	SHA256     string `json:"SHA256"`
+}
+
+func newFile(name, key, exportType string, rows int, content []byte) File {
+	return File{Name: name, Key: key, ExportType: exportType, Rows: rows, Bytes: len(content), SHA256: checksum(content)}
+}
+
+//Manifest describes the files uploaded by a job, so consumers can tell which files belong to the same export and whether they are complete
+type Manifest struct {
+	JobID         string          `json:"JobID"`
+	StartTime     time.Time       `json:"StartTime"`
+	EndTime       time.Time       `json:"EndTime"`
+	Concepts      []string        `json:"Concepts"`
+	Options       concept.Options `json:"Options"`
+	Format        string          `json:"Format"`
+	SchemaVersion string          `json:"SchemaVersion"`
+	Files         []File          `json:"Files"`
+	Failed        []string        `json:"Failed,omitempty"`
+	RollbackOf    string          `json:"RollbackOf,omitempty"`
+}
+
+func newManifest(job *Job, format string) Manifest {
+	m := Manifest{
+		JobID:         job.ID,
+		Concepts:      job.Concepts,
+		Options:       job.options(),
+		Format:        format,
+		SchemaVersion: SchemaVersion,
+		Files:         job.Files,
+		Failed:        job.Failed,
+		RollbackOf:    job.RollbackOf,
+	}
+	if job.StartTime != nil {
+		m.StartTime = *job.StartTime
+	}
+	if job.EndTime != nil {
+		m.EndTime = *job.EndTime
+	}
+	return m
+}
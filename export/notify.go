@@ -0,0 +1,127 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	logger "github.com/Financial-Times/go-logger/v2"
+)
+
+const SignatureHeader = "X-Signature-256"
+
+//Notification is the payload posted to the callback URLs once a job finished
+type Notification struct {
+	JobID        string         `json:"JobID"`
+	Status       concept.State  `json:"Status"`
+	Published    bool           `json:"Published"`
+	Concepts     []string       `json:"Concepts,omitempty"`
+	Failed       []string       `json:"Failed,omitempty"`
+	ErrorMessage string         `json:"ErrorMessage,omitempty"`
+	StartTime    *time.Time     `json:"StartTime,omitempty"`
+	EndTime      *time.Time     `json:"EndTime,omitempty"`
+	Counts       map[string]int `json:"Counts,omitempty"`
+	Files        []File         `json:"Files,omitempty"`
+	RollbackOf   string         `json:"RollbackOf,omitempty"`
+}
+
+func newNotification(job *Job) Notification {
+	n := Notification{
+		JobID:        job.ID,
+		Status:       job.Status,
+		Published:    job.Published,
+		Concepts:     job.Concepts,
+		Failed:       job.Failed,
+		ErrorMessage: job.ErrorMessage,
+		StartTime:    job.StartTime,
+		EndTime:      job.EndTime,
+		Files:        job.Files,
+		RollbackOf:   job.RollbackOf,
+	}
+	//the rows written to the uploaded files, which also cover the types a retry exported again
+	for _, f := range job.Files {
+		if n.Counts == nil {
+			n.Counts = map[string]int{}
+		}
+		n.Counts[f.ExportType] = f.Rows
+	}
+	return n
+}
+
+const defaultNotifyTimeout = time.Minute
+
+//Notifier posts the notification of a finished job to the callback URLs of the job and to the ones configured for every job.
+//With a secret, the payload is signed with HMAC-SHA256 in the X-Signature-256 header as sha256=<hex digest>
+type Notifier struct {
+	Client concept.Client
+	URLs   []string
+	Secret string
+	Log    *logger.UPPLogger
+	//Timeout bounds the notification of a job to every URL, retries included. Zero is a minute
+	Timeout time.Duration
+}
+
+//Notify posts the notification of the job to every URL, giving up on the remaining ones once the timeout elapsed
+func (n *Notifier) Notify(job *Job, tid string) {
+	timeout := n.Timeout
+	if timeout == 0 {
+		timeout = defaultNotifyTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logEntry := n.Log.WithTransactionID(tid)
+	urls := append(append([]string{}, n.URLs...), job.Callbacks...)
+	if len(urls) == 0 {
+		return
+	}
+	payload, err := json.Marshal(newNotification(job))
+	if err != nil {
+		logEntry.WithError(err).Errorf("Building notification of job %v failed", job.ID)
+		return
+	}
+	for _, url := range urls {
+		if err := n.post(ctx, url, payload, tid); err != nil {
+			logEntry.WithError(err).Errorf("Notifying %v of job %v failed", url, job.ID)
+			continue
+		}
+		logEntry.Infof("Notified %v of job %v", url, job.ID)
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, url string, payload []byte, tid string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("User-Agent", "UPP Concept Exporter")
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Request-Id", tid)
+	if n.Secret != "" {
+		req.Header.Add(SignatureHeader, Sign(payload, n.Secret))
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned HTTP %v", resp.StatusCode)
+	}
+	return nil
+}
+
+//Sign returns the signature of the payload sent in the X-Signature-256 header
+func Sign(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
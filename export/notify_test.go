@@ -0,0 +1,127 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/sethgrid/pester"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFullExporter_RunFullExportNotifiesCallbacks(t *testing.T) {
+	notifications := make(chan Notification, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "tid_1234", r.Header.Get("X-Request-Id"))
+		assert.Equal(t, Sign(body, "secret"), r.Header.Get(SignatureHeader))
+		var n Notification
+		assert.NoError(t, json.Unmarshal(body, &n))
+		notifications <- n
+	}))
+	defer server.Close()
+
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+	exporter.Notifier = &Notifier{Client: &http.Client{}, URLs: []string{server.URL + "/configured"}, Secret: "secret", Log: log}
+
+	inquirer.On("Inquire", []string{"Brand"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"})})
+	updater.On("Upload", mock.Anything, mock.Anything, "tid_1234").Return(nil)
+	job, err := exporter.QueueJob(context.Background(), []string{"Brand"}, concept.Options{}, []string{server.URL + "/requested"}, "", "tid_1234")
+	assert.NoError(t, err)
+
+	n := <-notifications
+	assert.Equal(t, job.ID, n.JobID)
+	assert.Equal(t, concept.FINISHED, n.Status)
+	assert.True(t, n.Published)
+	assert.Equal(t, "Brand.csv", n.Files[0].Key)
+	assert.Equal(t, 1, n.Files[0].Rows)
+	assert.Equal(t, map[string]int{"Brand": 1}, n.Counts)
+	assert.Equal(t, n, <-notifications)
+}
+
+//newRetryingClient retries like the client of the service, without waiting between the attempts
+func newRetryingClient() *pester.Client {
+	client := pester.NewExtendedClient(&http.Client{})
+	client.Backoff = func(int) time.Duration { return 0 }
+	client.MaxRetries = 3
+	return client
+}
+
+func TestNotifier_NotifyFailingCallback(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	notifier := &Notifier{Client: &http.Client{}, Log: logger.NewUPPLogger("Test", "PANIC")}
+	notifier.Notify(&Job{ID: "job_1", Callbacks: []string{server.URL}}, "tid_1234")
+
+	assert.Equal(t, 1, calls)
+	assert.EqualError(t, notifier.post(context.Background(), server.URL, []byte("{}"), "tid_1234"), "callback returned HTTP 503")
+}
+
+func TestNotifier_NotifyTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	notifier := &Notifier{Client: newRetryingClient(), Log: logger.NewUPPLogger("Test", "PANIC"), Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	notifier.Notify(&Job{ID: "job_1", Callbacks: []string{server.URL, server.URL}}, "tid_1234")
+
+	assert.True(t, time.Since(start) < time.Second, "the notification should give up once the timeout elapsed")
+}
+
+func TestNotifier_NotifyRetriesUnavailableCallback(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	notifier := &Notifier{Client: newRetryingClient(), Log: logger.NewUPPLogger("Test", "PANIC")}
+
+	assert.NoError(t, notifier.post(context.Background(), server.URL, []byte(`{"JobID":"job_1"}`), "tid_1234"))
+	assert.Equal(t, []string{`{"JobID":"job_1"}`, `{"JobID":"job_1"}`, `{"JobID":"job_1"}`}, bodies)
+}
+
+func TestNotifier_NotifyRejectingCallback(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	notifier := &Notifier{Client: newRetryingClient(), Log: logger.NewUPPLogger("Test", "PANIC")}
+
+	assert.EqualError(t, notifier.post(context.Background(), server.URL, []byte("{}"), "tid_1234"), "callback returned HTTP 400")
+	assert.Equal(t, 1, calls, "a client error is not retried")
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "sha256=4dfa5685d47b539ddb8d3b9bcc25b123f1eb9101b7b12fae2a870a121532329d", Sign([]byte(`{"JobID":"job_1"}`), "secret"))
+}
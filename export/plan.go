@@ -0,0 +1,127 @@
+package export
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+)
+
+//Preflight is a check of a dependency of the exports, run by the dry runs with their transaction ID
+type Preflight struct {
+	Name  string
+	Check func(tid string) (string, error)
+}
+
+//CheckResult is the outcome of a preflight check
+type CheckResult struct {
+	Name    string `json:"Name"`
+	OK      bool   `json:"OK"`
+	Message string `json:"Message"`
+}
+
+//PlannedFile is a file a job would upload. Only the concept types are counted, and their size is estimated from the
+//bytes per row of the latest file of the same export type in the job history
+type PlannedFile struct {
+	ExportType     string `json:"ExportType"`
+	Name           string `json:"Name"`
+	Rows           *int   `json:"Rows,omitempty"`
+	EstimatedBytes *int   `json:"EstimatedBytes,omitempty"`
+	ErrorMessage   string `json:"ErrorMessage,omitempty"`
+}
+
+//Plan is what a job would export and whether it would likely succeed, found without reading the records nor uploading any file of the export
+type Plan struct {
+	DryRun         bool            `json:"DryRun"`
+	Concepts       []string        `json:"Concepts"`
+	Options        concept.Options `json:"Options"`
+	Files          []PlannedFile   `json:"Files"`
+	Rows           int             `json:"Rows"`
+	EstimatedBytes int             `json:"EstimatedBytes"`
+	Checks         []CheckResult   `json:"Checks"`
+	Ready          bool            `json:"Ready"`
+}
+
+//Plan counts the concepts of the candidates per concept type and runs the preflight checks, without creating a job
+func (fe *FullExporter) Plan(ctx context.Context, candidates []string, opts concept.Options, tid string) Plan {
+	plan := Plan{DryRun: true, Concepts: candidates, Options: opts, Files: []PlannedFile{}, Checks: []CheckResult{}, Ready: true}
+	counted := map[string]bool{}
+	if opts.Kind != concept.COOCCURRENCES && opts.Kind != concept.TRENDING {
+		for _, cType := range candidates {
+			counted[cType] = true
+		}
+	}
+	for _, exportType := range opts.ExportTypes(candidates) {
+		file := PlannedFile{ExportType: exportType, Name: fe.Exporter.GetFileName(exportType)}
+		if counted[exportType] && fe.Counter != nil {
+			rows, err := fe.Counter.Count(ctx, exportType, opts, tid)
+			if err != nil {
+				fe.Log.WithTransactionID(tid).WithError(err).Warnf("Counting %v failed", exportType)
+				file.ErrorMessage = err.Error()
+				plan.Ready = false
+			} else {
+				file.Rows = &rows
+				plan.Rows += rows
+				if bytesPerRow, ok := fe.bytesPerRow(exportType); ok {
+					bytes := int(bytesPerRow * float64(rows))
+					file.EstimatedBytes = &bytes
+					plan.EstimatedBytes += bytes
+				}
+			}
+		}
+		plan.Files = append(plan.Files, file)
+	}
+	checks := []Preflight{{Name: "Job queue", Check: fe.checkQueue}}
+	if fe.Leader != nil {
+		checks = append([]Preflight{{Name: "Leader", Check: fe.checkLeader}}, checks...)
+	}
+	for _, p := range append(checks, fe.Preflights...) {
+		msg, err := p.Check(tid)
+		result := CheckResult{Name: p.Name, OK: err == nil, Message: msg}
+		if err != nil {
+			result.Message = msg + " " + err.Error()
+			plan.Ready = false
+		}
+		plan.Checks = append(plan.Checks, result)
+	}
+	return plan
+}
+
+//checkLeader tells whether this replica runs the jobs, as the other replicas reject them
+func (fe *FullExporter) checkLeader(_ string) (string, error) {
+	if !fe.isLeader() {
+		return "The job would be rejected.", ErrNotLeader
+	}
+	return "The replica is the leader.", nil
+}
+
+//checkQueue tells whether the job would be run right away or queued, rather than rejected as QueueSize jobs are already waiting
+func (fe *FullExporter) checkQueue(_ string) (string, error) {
+	fe.RLock()
+	defer fe.RUnlock()
+	if fe.idle() {
+		return "The job would be run right away.", nil
+	}
+	if len(fe.queue) >= fe.QueueSize {
+		return "The job would be rejected.", ErrQueueFull
+	}
+	return fmt.Sprintf("The job would be queued at position %d.", len(fe.queue)+1), nil
+}
+
+//bytesPerRow is the average size of a row in the latest file of the export type in the job history
+func (fe *FullExporter) bytesPerRow(exportType string) (float64, bool) {
+	fe.RLock()
+	defer fe.RUnlock()
+	jobs := append(append([]*Job{}, fe.history...), fe.job)
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if jobs[i] == nil {
+			continue
+		}
+		for _, f := range jobs[i].Files {
+			if f.ExportType == exportType && f.Rows > 0 {
+				return float64(f.Bytes) / float64(f.Rows), true
+			}
+		}
+	}
+	return 0, false
+}
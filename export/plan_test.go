@@ -0,0 +1,109 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockCounter struct {
+	mock.Mock
+}
+
+func (m *mockCounter) Count(_ context.Context, conceptType string, opts concept.Options, tid string) (int, error) {
+	args := m.Called(conceptType, opts, tid)
+	return args.Int(0), args.Error(1)
+}
+
+func TestFullExporter_Plan(t *testing.T) {
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	counter := new(mockCounter)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC"))
+	exporter.Counter = counter
+	exporter.Preflights = []Preflight{{Name: "S3 Writer", Check: func(tid string) (string, error) {
+		assert.Equal(t, "tid_1234", tid)
+		return "S3 Writer is good to go.", nil
+	}}}
+	exporter.history = []*Job{{ID: "job_1", Files: []File{{Name: "Brand.csv", ExportType: "Brand", Rows: 10, Bytes: 1000}}}}
+
+	opts := concept.Options{Annotations: true}
+	counter.On("Count", "Brand", opts, "tid_1234").Return(5, nil)
+	counter.On("Count", "Person", opts, "tid_1234").Return(7, nil)
+
+	plan := exporter.Plan(context.Background(), []string{"Brand", "Person"}, opts, "tid_1234")
+
+	five, seven, fiveHundred := 5, 7, 500
+	assert.Equal(t, Plan{
+		DryRun:   true,
+		Concepts: []string{"Brand", "Person"},
+		Options:  opts,
+		Files: []PlannedFile{
+			{ExportType: "Brand", Name: "Brand.csv", Rows: &five, EstimatedBytes: &fiveHundred},
+			{ExportType: "Person", Name: "Person.csv", Rows: &seven},
+			{ExportType: concept.Annotations, Name: "Annotations.csv"},
+		},
+		Rows:           12,
+		EstimatedBytes: 500,
+		Checks: []CheckResult{
+			{Name: "Job queue", OK: true, Message: "The job would be run right away."},
+			{Name: "S3 Writer", OK: true, Message: "S3 Writer is good to go."},
+		},
+		Ready:          true,
+	}, plan)
+	assert.Nil(t, exporter.GetCurrentJob().Workers)
+	updater.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything)
+	inquirer.AssertNotCalled(t, "Inquire", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFullExporter_PlanNotReady(t *testing.T) {
+	counter := new(mockCounter)
+	exporter := NewFullExporter(1, new(mockUpdater), new(mockInquirer), NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC"))
+	exporter.Counter = counter
+	exporter.Preflights = []Preflight{{Name: "S3 Writer", Check: func(string) (string, error) {
+		return "S3 Writer is not good to go.", errors.New("GTG HTTP status code is 503")
+	}}}
+	counter.On("Count", "Brand", concept.Options{}, "tid_1234").Return(0, errors.New("Neo err"))
+
+	plan := exporter.Plan(context.Background(), []string{"Brand"}, concept.Options{}, "tid_1234")
+
+	assert.False(t, plan.Ready)
+	assert.Equal(t, []PlannedFile{{ExportType: "Brand", Name: "Brand.csv", ErrorMessage: "Neo err"}}, plan.Files)
+	assert.Equal(t, []CheckResult{
+		{Name: "Job queue", OK: true, Message: "The job would be run right away."},
+		{Name: "S3 Writer", OK: false, Message: "S3 Writer is not good to go. GTG HTTP status code is 503"},
+	}, plan.Checks)
+}
+
+func TestFullExporter_PlanQueueFull(t *testing.T) {
+	exporter := NewFullExporter(1, new(mockUpdater), new(mockInquirer), NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC"))
+	exporter.QueueSize = 1
+	exporter.job = &Job{ID: "job_1", Status: concept.RUNNING}
+
+	plan := exporter.Plan(context.Background(), []string{"Brand"}, concept.Options{}, "tid_1234")
+	assert.True(t, plan.Ready)
+	assert.Equal(t, []CheckResult{{Name: "Job queue", OK: true, Message: "The job would be queued at position 1."}}, plan.Checks)
+
+	exporter.queue = []*Job{{ID: "job_2", Status: concept.QUEUED}}
+	plan = exporter.Plan(context.Background(), []string{"Brand"}, concept.Options{}, "tid_1234")
+	assert.False(t, plan.Ready)
+	assert.Equal(t, []CheckResult{{Name: "Job queue", OK: false, Message: "The job would be rejected. job queue is full"}}, plan.Checks)
+}
+
+func TestFullExporter_PlanOnFollower(t *testing.T) {
+	exporter := NewFullExporter(1, new(mockUpdater), new(mockInquirer), NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC"))
+	exporter.Leader = &testLeader{leader: false}
+
+	plan := exporter.Plan(context.Background(), []string{"Brand"}, concept.Options{}, "tid_1234")
+
+	assert.False(t, plan.Ready)
+	assert.Equal(t, []CheckResult{
+		{Name: "Leader", OK: false, Message: "The job would be rejected. export jobs are run by the leader replica"},
+		{Name: "Job queue", OK: true, Message: "The job would be run right away."},
+	}, plan.Checks)
+}
@@ -0,0 +1,40 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+)
+
+const (
+	CSVFormat  = "csv"
+	JSONFormat = "json"
+)
+
+//Preview renders the records of the export type with a CsvExporter of its own, so the rows match the files of the jobs
+//without touching what the running job writes. The JSON format renders every row as an object keyed by the CSV columns
+func Preview(exportType string, opts concept.Options, records []db.Record, format string) ([]byte, error) {
+	switch format {
+	case CSVFormat:
+		e := NewCsvExporter()
+		if err := e.Prepare([]string{exportType}, opts); err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			if err := e.Write(r, exportType, ""); err != nil {
+				return nil, err
+			}
+		}
+		return e.GetBytes(exportType), nil
+	case JSONFormat:
+		header := getHeader(exportType, opts)
+		rows := make([]map[string]string, 0, len(records))
+		for _, r := range records {
+			rows = append(rows, row(header, r))
+		}
+		return json.Marshal(rows)
+	}
+	return nil, fmt.Errorf("unsupported preview format %v", format)
+}
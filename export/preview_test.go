@@ -0,0 +1,31 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreview(t *testing.T) {
+	records := []db.Record{
+		db.Concept{Id: "http://api.ft.com/things/org", PrefLabel: "Org, Inc.", ApiUrl: "http://api.ft.com/organisations/org", LeiCode: "lei", FactsetId: "f1;f2"},
+	}
+
+	content, err := Preview("Organisation", concept.Options{}, records, CSVFormat)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,prefLabel,apiUrl,leiCode,factsetId,FIGI\n"+
+		"http://api.ft.com/things/org,\"Org, Inc.\",http://api.ft.com/organisations/org,lei,f1;f2,\n", string(content))
+
+	content, err = Preview("Organisation", concept.Options{}, records, JSONFormat)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":"http://api.ft.com/things/org","prefLabel":"Org, Inc.","apiUrl":"http://api.ft.com/organisations/org","leiCode":"lei","factsetId":"f1;f2","FIGI":""}]`, string(content))
+
+	content, err = Preview("Brand", concept.Options{}, nil, JSONFormat)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(content))
+
+	_, err = Preview("Brand", concept.Options{}, records, "xml")
+	assert.EqualError(t, err, "unsupported preview format xml")
+}
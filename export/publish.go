@@ -0,0 +1,166 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/monitoring"
+)
+
+//jobsPrefix is the prefix of the keys of the files of a job published atomically without a key template
+const jobsPrefix = "jobs/"
+
+//KeyTemplate renders the object key of an exported file. It supports the {type}, {name}, {ext}, {jobId}, {yyyy}, {mm}, {dd} and {HH} placeholders,
+//e.g. {type}/{yyyy}/{mm}/{dd}/{jobId}.{ext}
+type KeyTemplate string
+
+var (
+	ErrKeyTemplateNotUnique = errors.New("key template must contain the {jobId} placeholder and the {type} or {name} one, so every file of every job has its own key")
+	placeholderPattern      = regexp.MustCompile(`{[^{}]*}`)
+	placeholders            = []string{"{type}", "{name}", "{ext}", "{jobId}", "{yyyy}", "{mm}", "{dd}", "{HH}"}
+)
+
+//Validate tells whether every key rendered by the template is unique to its file and job
+func (t KeyTemplate) Validate() error {
+	for _, placeholder := range placeholderPattern.FindAllString(string(t), -1) {
+		if !contains(placeholders, placeholder) {
+			return fmt.Errorf("key template has the unknown placeholder %v", placeholder)
+		}
+	}
+	if !strings.Contains(string(t), "{jobId}") || (!strings.Contains(string(t), "{type}") && !strings.Contains(string(t), "{name}")) {
+		return ErrKeyTemplateNotUnique
+	}
+	return nil
+}
+
+func (t KeyTemplate) Key(exportType, fileName, jobID string, date time.Time) string {
+	r := strings.NewReplacer(
+		"{type}", exportType,
+		"{name}", fileName,
+		"{ext}", strings.TrimPrefix(path.Ext(fileName), "."),
+		"{jobId}", jobID,
+		"{yyyy}", date.Format("2006"),
+		"{mm}", date.Format("01"),
+		"{dd}", date.Format("02"),
+		"{HH}", date.Format("15"),
+	)
+	return r.Replace(string(t))
+}
+
+//publish uploads the descriptors of the job, the latest manifest last. In atomic mode the files were only uploaded under the keys of the job,
+//so the latest manifest naming those keys switches consumers to the complete set of files in a single write
+func (fe *FullExporter) publish(ctx context.Context, tid string) {
+	if fe.KeyTemplate != "" || fe.AtomicPublish {
+		if fe.archiveDescriptors(ctx, tid) != nil {
+			return
+		}
+	}
+	locate := locateByName
+	if fe.AtomicPublish {
+		if len(fe.job.Failed) != 0 {
+			fe.Log.WithTransactionID(tid).Warnf("Not publishing job %v as exporting %v failed", fe.job.ID, fe.job.Failed)
+			fe.setJobErrorMessage(fmt.Sprintf("%s Files of the job are left unpublished", fe.job.ErrorMessage))
+			return
+		}
+		locate = locateByKey
+	}
+	if fe.uploadDescriptors(ctx, latestKey, locate, tid) == nil && len(fe.job.Failed) == 0 {
+		fe.setJobPublished()
+		if fe.AtomicPublish {
+			for _, f := range fe.job.Files {
+				monitoring.LastSuccess.WithLabelValues(f.ExportType).SetToCurrentTime()
+			}
+		}
+	}
+}
+
+//objectKey is the key the file of the given export type is uploaded to, next to its latest copy unless published atomically
+func (fe *FullExporter) objectKey(fileName, exportType string) string {
+	if fe.KeyTemplate != "" {
+		return fe.KeyTemplate.Key(exportType, fileName, fe.job.ID, *fe.job.StartTime)
+	}
+	if fe.AtomicPublish {
+		return jobsPrefix + fe.job.ID + "/" + fileName
+	}
+	return fileName
+}
+
+func latestKey(fileName, _ string) string {
+	return fileName
+}
+
+//locateByName locates a file by its latest copy under its well-known name
+func locateByName(f File) string {
+	return f.Name
+}
+
+//locateByKey locates a file by the key of its job
+func locateByKey(f File) string {
+	return f.Key
+}
+
+//archiveDescriptors uploads the descriptors of the job under its own keys, its manifest also under the key rollbacks find the job by
+func (fe *FullExporter) archiveDescriptors(ctx context.Context, tid string) error {
+	if err := fe.uploadDescriptors(ctx, fe.objectKey, locateByName, tid); err != nil {
+		return err
+	}
+	key := archivedManifestKey(fe.job.ID)
+	if fe.objectKey(ManifestFileName, descriptorType(ManifestFileName)) == key {
+		return nil
+	}
+	fe.RLock()
+	job := fe.getJob()
+	fe.RUnlock()
+	return fe.uploadJSON(ctx, newManifest(&job, fe.Exporter.Format()), key, descriptorType(ManifestFileName), tid)
+}
+
+//uploadDescriptors uploads the data package and then the manifest of the job under the keys returned by key, the resources of the data package being located by locate
+func (fe *FullExporter) uploadDescriptors(ctx context.Context, key func(fileName, exportType string) string, locate func(File) string, tid string) error {
+	fe.RLock()
+	job := fe.getJob()
+	fe.RUnlock()
+	var exportTypes []string
+	for _, f := range job.Files {
+		exportTypes = append(exportTypes, f.ExportType)
+	}
+	dataPackage := fe.Exporter.DataPackage(exportTypes, job.options())
+	for i, f := range job.Files {
+		dataPackage.Resources[i].Path = locate(f)
+	}
+	err := fe.uploadJSON(ctx, dataPackage, key(DataPackageFileName, descriptorType(DataPackageFileName)), descriptorType(DataPackageFileName), tid)
+	if err != nil {
+		return err
+	}
+	return fe.uploadJSON(ctx, newManifest(&job, fe.Exporter.Format()), key(ManifestFileName, descriptorType(ManifestFileName)), descriptorType(ManifestFileName), tid)
+}
+
+func descriptorType(fileName string) string {
+	return strings.TrimSuffix(fileName, path.Ext(fileName))
+}
+
+func (fe *FullExporter) uploadJSON(ctx context.Context, v interface{}, fileName, exportType, tid string) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err == nil {
+		err = fe.upload(ctx, content, fileName, exportType, tid)
+	}
+	if err != nil {
+		fe.Log.WithTransactionID(tid).Errorf("Uploading %v failed: %v", fileName, err)
+		fe.setJobErrorMessage(fmt.Sprintf("%s %s", fe.job.ErrorMessage, err.Error()))
+	}
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
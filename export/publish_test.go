@@ -0,0 +1,97 @@
+package export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyTemplate_Key(t *testing.T) {
+	date := time.Date(2020, 3, 7, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template KeyTemplate
+		expected string
+	}{
+		{
+			name:     "Date partitioned",
+			template: "{type}/{yyyy}/{mm}/{dd}/{jobId}.{ext}",
+			expected: "Brand/2020/03/07/job_1.csv",
+		},
+		{
+			name:     "Hourly with file name",
+			template: "exports/{yyyy}-{mm}-{dd}T{HH}/{name}",
+			expected: "exports/2020-03-07T09/Brand.csv",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.template.Key("Brand", "Brand.csv", "job_1", date))
+		})
+	}
+}
+
+func TestKeyTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template KeyTemplate
+		err      string
+	}{
+		{
+			name:     "Date partitioned",
+			template: "{type}/{yyyy}/{mm}/{dd}/{jobId}.{ext}",
+		},
+		{
+			name:     "Without job ID",
+			template: "exports/{yyyy}-{mm}-{dd}T{HH}/{name}",
+			err:      ErrKeyTemplateNotUnique.Error(),
+		},
+		{
+			name:     "Without file",
+			template: "{yyyy}/{jobId}.{ext}",
+			err:      ErrKeyTemplateNotUnique.Error(),
+		},
+		{
+			name:     "Unknown placeholder",
+			template: "{type}/{jobID}.{ext}",
+			err:      "key template has the unknown placeholder {jobID}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.template.Validate()
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestFullExporter_RunFullExportArchivesWithKeyTemplate(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+	exporter.KeyTemplate = "{type}/{jobId}.{ext}"
+
+	inquirer.On("Inquire", []string{"Brand"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"})})
+	uploads := recordUploads(updater, "tid_1234")
+
+	job := runTestJob(t, exporter, []string{"Brand"}, concept.Options{})
+
+	assert.Equal(t, []string{"Brand/" + job.ID + ".csv", "Brand.csv", "datapackage/" + job.ID + ".json", "manifest/" + job.ID + ".json", "jobs/" + job.ID + "/" + ManifestFileName, DataPackageFileName, ManifestFileName}, uploads.names)
+	current := exporter.GetCurrentJob()
+	assert.True(t, current.Published)
+	assert.Equal(t, "Brand.csv", current.Files[0].Name)
+	assert.Equal(t, "Brand/"+job.ID+".csv", current.Files[0].Key)
+}
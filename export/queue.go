@@ -0,0 +1,119 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/event"
+	"github.com/Financial-Times/concept-exporter/tracing"
+	"github.com/pborman/uuid"
+)
+
+var (
+	ErrQueueFull  = errors.New("job queue is full")
+	ErrJobRunning = errors.New("a job is already running")
+	ErrNotLeader  = errors.New("export jobs are run by the leader replica")
+	ErrLeaseLost  = errors.New("job was cancelled as the replica lost the lease")
+)
+
+//QueueJob creates a job and runs it right away when the exporter is idle. Otherwise the job is queued behind the current and already queued ones,
+//to be run in order once they finished, unless QueueSize jobs are already waiting. The job is traced as part of the trace of the given context
+func (fe *FullExporter) QueueJob(ctx context.Context, candidates []string, opts concept.Options, callbacks []string, errMsg, tid string) (Job, error) {
+	return fe.createJob(ctx, candidates, opts, callbacks, errMsg, tid, true)
+}
+
+//StartJob creates a job and runs it right away when the exporter is idle, failing with ErrJobRunning otherwise
+func (fe *FullExporter) StartJob(ctx context.Context, candidates []string, opts concept.Options, errMsg, tid string) (Job, error) {
+	return fe.createJob(ctx, candidates, opts, nil, errMsg, tid, false)
+}
+
+func (fe *FullExporter) createJob(ctx context.Context, candidates []string, opts concept.Options, callbacks []string, errMsg, tid string, wait bool) (Job, error) {
+	var created event.Event
+	defer func() {
+		fe.publishEvent(created)
+	}()
+	fe.Lock()
+	defer fe.Unlock()
+	job := &Job{ID: "job_" + uuid.New(), NrWorker: fe.NrOfConcurrentWorkers, Status: concept.STARTING, Concepts: candidates, ErrorMessage: errMsg, Options: optionsOf(opts), Callbacks: callbacks}
+	if err := fe.enqueue(ctx, job, tid, wait); err != nil {
+		return Job{}, err
+	}
+	created = event.Event{Type: event.JobCreated, JobID: job.ID, TransactionID: tid, Concepts: candidates}
+	return fe.copyJob(job), nil
+}
+
+//Authorize approves the concept types of a job derived from another one before it's created, failing with the reason otherwise
+type Authorize func(concepts []string) error
+
+func (a Authorize) check(concepts []string) error {
+	if a == nil {
+		return nil
+	}
+	return a(concepts)
+}
+
+//enqueue runs the job right away when the exporter is idle. Otherwise the job is queued when wait is set, unless QueueSize jobs are already waiting.
+//The caller holds the lock, so checking whether the exporter is idle and taking it are one step
+func (fe *FullExporter) enqueue(ctx context.Context, job *Job, tid string, wait bool) error {
+	if !fe.isLeader() {
+		return ErrNotLeader
+	}
+	job.tid = tid
+	job.ctx = tracing.Detach(ctx)
+	if fe.idle() {
+		fe.setJob(job)
+		go fe.RunFullExport(job.ctx, tid)
+		return nil
+	}
+	if !wait {
+		return ErrJobRunning
+	}
+	if len(fe.queue) >= fe.QueueSize {
+		return ErrQueueFull
+	}
+	job.Status = concept.QUEUED
+	fe.queue = append(fe.queue, job)
+	fe.Log.WithTransactionID(tid).Infof("Queued job %v at position %d", job.ID, len(fe.queue))
+	return nil
+}
+
+//idle tells whether a job would be run right away, neither running one nor having queued ones. The caller holds the lock
+func (fe *FullExporter) idle() bool {
+	return !fe.running && len(fe.queue) == 0 && (fe.job == nil || fe.job.Status == concept.FINISHED)
+}
+
+//runNext ends the run of the current job and runs the first queued job, if any. The queued jobs are dropped once this replica isn't the leader anymore
+func (fe *FullExporter) runNext() {
+	fe.Lock()
+	fe.running = false
+	if len(fe.queue) == 0 {
+		fe.Unlock()
+		return
+	}
+	if !fe.isLeader() {
+		fe.dropQueue()
+		fe.Unlock()
+		return
+	}
+	next := fe.queue[0]
+	fe.queue = fe.queue[1:]
+	next.Status = concept.STARTING
+	fe.setJob(next)
+	fe.Unlock()
+	go fe.RunFullExport(next.ctx, next.tid)
+}
+
+//dropQueue finishes the queued jobs without running them
+func (fe *FullExporter) dropQueue() {
+	now := time.Now().UTC()
+	for _, job := range fe.queue {
+		fe.Log.WithTransactionID(job.tid).Warnf("Dropping queued job %v as the replica lost the lease", job.ID)
+		job.Status = concept.FINISHED
+		job.ErrorMessage = ErrLeaseLost.Error()
+		job.EndTime = &now
+		fe.setJob(job)
+	}
+	fe.queue = nil
+}
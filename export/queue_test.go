@@ -0,0 +1,131 @@
+package export
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+//waitForJob waits until the job finished and the exporter is idle again
+func waitForJob(t *testing.T, exporter *FullExporter, id string) {
+	assert.Eventually(t, func() bool {
+		job, _ := exporter.GetJob(id)
+		return job.Status == concept.FINISHED && !exporter.IsRunningJob()
+	}, time.Second, time.Millisecond)
+}
+
+//runTestJob starts a job of the given concepts and waits until it finished
+func runTestJob(t *testing.T, exporter *FullExporter, candidates []string, opts concept.Options) *Job {
+	job, err := exporter.StartJob(context.Background(), candidates, opts, "", "tid_1234")
+	assert.NoError(t, err)
+	waitForJob(t, exporter, job.ID)
+	return &job
+}
+
+func TestFullExporter_QueueJob(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+	exporter.QueueSize = 1
+
+	blocked := &concept.Worker{ConceptType: "Brand", RecordCh: make(chan db.Record), Errch: make(chan error, 2), Status: concept.STARTING}
+	inquirer.On("Inquire", []string{"Brand"}, concept.Options{}, "tid_1").Return([]*concept.Worker{blocked})
+	inquirer.On("Inquire", []string{"Topic"}, concept.Options{}, "tid_2").Return([]*concept.Worker{newTestWorker("Topic", db.Concept{Id: "topic"})})
+	updater.On("Upload", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	first, err := exporter.QueueJob(context.Background(), []string{"Brand"}, concept.Options{}, nil, "", "tid_1")
+	assert.NoError(t, err)
+	assert.Equal(t, concept.STARTING, first.Status)
+	assert.Eventually(t, func() bool { return exporter.GetCurrentJob().Status == concept.RUNNING }, time.Second, time.Millisecond)
+
+	second, err := exporter.QueueJob(context.Background(), []string{"Topic"}, concept.Options{}, nil, "", "tid_2")
+	assert.NoError(t, err)
+	assert.Equal(t, concept.QUEUED, second.Status)
+	assert.Equal(t, 1, second.Position)
+	assert.True(t, exporter.IsRunningJob())
+
+	_, err = exporter.StartJob(context.Background(), []string{"Topic"}, concept.Options{}, "", "tid_3")
+	assert.Equal(t, ErrJobRunning, err)
+
+	_, err = exporter.QueueJob(context.Background(), []string{"Topic"}, concept.Options{}, nil, "", "tid_3")
+	assert.Equal(t, ErrQueueFull, err)
+
+	close(blocked.RecordCh)
+	assert.Eventually(t, func() bool {
+		job, _ := exporter.GetJob(second.ID)
+		return job.Status == concept.FINISHED
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return !exporter.IsRunningJob() }, time.Second, time.Millisecond)
+
+	jobs := exporter.GetJobs()
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, first.ID, jobs[0].ID)
+	assert.Equal(t, concept.FINISHED, jobs[0].Status)
+	assert.Equal(t, second.ID, jobs[1].ID)
+	assert.Zero(t, jobs[1].Position)
+}
+
+type testLeader struct {
+	sync.Mutex
+	leader bool
+}
+
+func (l *testLeader) IsLeader() bool {
+	l.Lock()
+	defer l.Unlock()
+	return l.leader
+}
+
+func (l *testLeader) set(leader bool) {
+	l.Lock()
+	defer l.Unlock()
+	l.leader = leader
+}
+
+func TestFullExporter_QueueJobOnFollower(t *testing.T) {
+	exporter := NewFullExporter(1, new(mockUpdater), new(mockInquirer), NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC"))
+	exporter.Leader = &testLeader{}
+
+	_, err := exporter.QueueJob(context.Background(), []string{"Brand"}, concept.Options{}, nil, "", "tid_1")
+
+	assert.Equal(t, ErrNotLeader, err)
+	assert.Empty(t, exporter.GetJobs())
+}
+
+func TestFullExporter_RunFullExportCancelledWhenLeaseLost(t *testing.T) {
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC"))
+	leader := &testLeader{leader: true}
+	exporter.Leader = leader
+	exporter.LeaderCheckInterval = time.Millisecond
+
+	blocked := &concept.Worker{ConceptType: "Brand", RecordCh: make(chan db.Record), Errch: make(chan error, 2), Status: concept.STARTING}
+	inquirer.On("Inquire", []string{"Brand"}, concept.Options{}, "tid_1").Return([]*concept.Worker{blocked})
+	first, err := exporter.QueueJob(context.Background(), []string{"Brand"}, concept.Options{}, nil, "", "tid_1")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return exporter.GetCurrentJob().Status == concept.RUNNING }, time.Second, time.Millisecond)
+	queued, err := exporter.QueueJob(context.Background(), []string{"Topic"}, concept.Options{}, nil, "", "tid_2")
+	assert.NoError(t, err)
+
+	leader.set(false)
+	waitForJob(t, exporter, first.ID)
+
+	job, _ := exporter.GetJob(first.ID)
+	assert.Equal(t, []string{"Brand"}, job.Failed)
+	assert.False(t, job.Published)
+	assert.Contains(t, job.ErrorMessage, ErrLeaseLost.Error())
+	job, _ = exporter.GetJob(queued.ID)
+	assert.Equal(t, concept.FINISHED, job.Status)
+	assert.Equal(t, ErrLeaseLost.Error(), job.ErrorMessage)
+	inquirer.AssertNotCalled(t, "Inquire", []string{"Topic"}, concept.Options{}, "tid_2")
+	updater.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/event"
+	"github.com/pborman/uuid"
+)
+
+var ErrJobNotRetryable = errors.New("job is not a finished export with failed types")
+
+//CreateRetryJob creates a job exporting the failed types of the given job again, with the options of its request.
+//The files of the given job which did not fail are published again along with the retried ones, so the retry describes the complete export.
+//Its files which were not archived under keys of their own are exported again as well, as a later job may have overwritten them.
+//The job is run like the requested ones, right away or once the queued jobs finished, if authorize approves its concept types
+func (fe *FullExporter) CreateRetryJob(ctx context.Context, jobID, tid string, authorize Authorize) (Job, error) {
+	var created event.Event
+	defer func() {
+		fe.publishEvent(created)
+	}()
+	fe.Lock()
+	defer fe.Unlock()
+	parent := fe.findJob(jobID)
+	if parent == nil {
+		return Job{}, ErrJobNotFound
+	}
+	if parent.Status != concept.FINISHED || len(parent.Failed) == 0 || parent.RollbackOf != "" {
+		return Job{}, ErrJobNotRetryable
+	}
+	if err := authorize.check(parent.Concepts); err != nil {
+		return Job{}, err
+	}
+	opts := parent.options()
+	opts.Types = append([]string{}, parent.Failed...)
+	var retried []File
+	for _, f := range parent.Files {
+		if contains(parent.Failed, f.ExportType) {
+			continue
+		}
+		if !fe.republishable(f) {
+			opts.Types = append(opts.Types, f.ExportType)
+			continue
+		}
+		retried = append(retried, f)
+	}
+	job := &Job{ID: "job_" + uuid.New(), NrWorker: fe.NrOfConcurrentWorkers, Status: concept.STARTING, Concepts: parent.Concepts, Options: optionsOf(opts), Callbacks: parent.Callbacks, RetryOf: parent.ID, retriedFiles: retried}
+	if err := fe.enqueue(ctx, job, tid, true); err != nil {
+		return Job{}, err
+	}
+	created = event.Event{Type: event.JobCreated, JobID: job.ID, TransactionID: tid, Concepts: parent.Concepts}
+	return fe.copyJob(job), nil
+}
+
+//republishable tells whether the file of a retried job can be published again. Unless published atomically, it's fetched from its archived copy,
+//which only exists when it was uploaded under a key of its own
+func (fe *FullExporter) republishable(f File) bool {
+	return fe.AtomicPublish || (f.Key != f.Name && fe.Fetcher != nil)
+}
+
+//addRetriedFiles adds the files of the retried job which did not fail to the files of the job. Unless published atomically, where the files stay under
+//the keys of the retried job, the archived ones are uploaded again under their well-known names, as a later job may have overwritten them
+func (fe *FullExporter) addRetriedFiles(ctx context.Context, tid string) {
+	fe.RLock()
+	files := fe.job.retriedFiles
+	fe.RUnlock()
+	for _, f := range files {
+		if !fe.AtomicPublish {
+			content, err := fe.fetchArchived(f, tid)
+			if err == nil {
+				err = fe.upload(ctx, content, f.Name, f.ExportType, tid)
+			}
+			if err != nil {
+				fe.Log.WithTransactionID(tid).Errorf("Republishing %v of job %v failed: %v", f.Name, fe.job.RetryOf, err)
+				fe.setJobFailed(f.ExportType)
+				fe.setJobErrorMessage(fmt.Sprintf("%s %s", fe.job.ErrorMessage, err.Error()))
+				continue
+			}
+		}
+		fe.addJobFile(f)
+	}
+}
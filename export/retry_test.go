@@ -0,0 +1,143 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFullExporter_RunFullExportRetriesFailedTypes(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+	exporter.Retries = 2
+
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"}), newFailingTestWorker("Topic", errors.New("Neo err"))}).Once()
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{Types: []string{"Topic"}}, "tid_1234").
+		Return([]*concept.Worker{newFailingTestWorker("Topic", errors.New("Neo err"))}).Once()
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{Types: []string{"Topic"}}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Topic", db.Concept{Id: "topic"})}).Once()
+	var topicCsv []byte
+	updater.On("Upload", mock.Anything, "Topic.csv", "tid_1234").Run(func(args mock.Arguments) {
+		topicCsv = args.Get(0).([]byte)
+	}).Return(nil)
+	updater.On("Upload", mock.Anything, mock.Anything, "tid_1234").Return(nil)
+
+	runTestJob(t, exporter, []string{"Brand", "Topic"}, concept.Options{})
+
+	job := exporter.GetCurrentJob()
+	assert.Empty(t, job.Failed)
+	assert.True(t, job.Published)
+	assert.Len(t, job.Workers, 4)
+	assert.Len(t, job.Files, 2)
+	assert.Equal(t, "id,prefLabel,apiUrl\ntopic,,\n", string(topicCsv))
+	inquirer.AssertExpectations(t)
+}
+
+func TestFullExporter_CreateRetryJob(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+
+	opts := concept.Options{Annotations: true}
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, opts, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"}), newFailingTestWorker("Topic", errors.New("Neo err")), newFailingTestWorker(concept.Annotations, errors.New("Neo err"))})
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{Annotations: true, Types: []string{"Topic", concept.Annotations, "Brand"}}, "tid_5678").Return([]*concept.Worker{})
+	updater.On("Upload", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	parent := runTestJob(t, exporter, []string{"Brand", "Topic"}, opts)
+
+	denied := errors.New("denied")
+	_, err := exporter.CreateRetryJob(context.Background(), parent.ID, "tid_5678", func(concepts []string) error {
+		assert.Equal(t, []string{"Brand", "Topic"}, concepts)
+		return denied
+	})
+	assert.Equal(t, denied, err)
+	assert.Len(t, exporter.GetJobs(), 1, "no job should be created when its concept types are not authorized")
+
+	job, err := exporter.CreateRetryJob(context.Background(), parent.ID, "tid_5678", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, parent.ID, job.RetryOf)
+	assert.Equal(t, []string{"Brand", "Topic"}, job.Concepts)
+	assert.Equal(t, concept.Options{Annotations: true, Types: []string{"Topic", concept.Annotations, "Brand"}}, *job.Options, "Brand.csv was not archived, so it's exported again")
+
+	_, err = exporter.CreateRetryJob(context.Background(), job.ID, "tid_5678", nil)
+	assert.Equal(t, ErrJobNotRetryable, err)
+	_, err = exporter.CreateRetryJob(context.Background(), "job_unknown", "tid_5678", nil)
+	assert.Equal(t, ErrJobNotFound, err)
+	waitForJob(t, exporter, job.ID)
+}
+
+func TestFullExporter_RunFullExportPublishesRetriedJobFiles(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+	exporter.AtomicPublish = true
+
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"}), newFailingTestWorker("Topic", errors.New("Neo err"))})
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{Types: []string{"Topic"}}, "tid_5678").
+		Return([]*concept.Worker{newTestWorker("Topic", db.Concept{Id: "topic"})})
+	var manifest Manifest
+	updater.On("Upload", mock.Anything, ManifestFileName, "tid_5678").Run(func(args mock.Arguments) {
+		assert.NoError(t, json.Unmarshal(args.Get(0).([]byte), &manifest))
+	}).Return(nil)
+	updater.On("Upload", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	parent := runTestJob(t, exporter, []string{"Brand", "Topic"}, concept.Options{})
+
+	job, err := exporter.CreateRetryJob(context.Background(), parent.ID, "tid_5678", nil)
+	assert.NoError(t, err)
+	waitForJob(t, exporter, job.ID)
+
+	current := exporter.GetCurrentJob()
+	assert.True(t, current.Published)
+	assert.Len(t, current.Files, 2)
+	assert.Equal(t, job.ID, manifest.JobID)
+	var keys []string
+	for _, f := range manifest.Files {
+		keys = append(keys, f.Key)
+	}
+	assert.ElementsMatch(t, []string{"jobs/" + parent.ID + "/Brand.csv", "jobs/" + job.ID + "/Topic.csv"}, keys)
+}
+
+func TestFullExporter_RunFullExportRepublishesArchivedRetriedFiles(t *testing.T) {
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	fetcher := new(mockFetcher)
+	exporter := newArchivingTestExporter(updater, inquirer, fetcher)
+
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"}), newFailingTestWorker("Topic", errors.New("Neo err"))})
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{Types: []string{"Topic"}}, "tid_5678").
+		Return([]*concept.Worker{newTestWorker("Topic", db.Concept{Id: "topic"})})
+	var brandCsv []byte
+	updater.On("Upload", mock.Anything, "Brand.csv", "tid_1234").Run(func(args mock.Arguments) {
+		brandCsv = args.Get(0).([]byte)
+	}).Return(nil)
+	updater.On("Upload", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	parent := runTestJob(t, exporter, []string{"Brand", "Topic"}, concept.Options{})
+	fetcher.On("Fetch", "Brand/"+parent.ID+".csv", "tid_5678").Return(brandCsv, nil)
+
+	job, err := exporter.CreateRetryJob(context.Background(), parent.ID, "tid_5678", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, concept.Options{Types: []string{"Topic"}}, *job.Options)
+	waitForJob(t, exporter, job.ID)
+
+	current := exporter.GetCurrentJob()
+	assert.Empty(t, current.Failed)
+	assert.True(t, current.Published)
+	assert.Len(t, current.Files, 2)
+	updater.AssertCalled(t, "Upload", brandCsv, "Brand.csv", "tid_5678")
+	fetcher.AssertExpectations(t)
+}
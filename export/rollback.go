@@ -0,0 +1,139 @@
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/event"
+	"github.com/pborman/uuid"
+)
+
+var (
+	ErrJobNotFound          = errors.New("job not found")
+	ErrJobNotRollbackable   = errors.New("job has no archived files of a successful export")
+	ErrRollbackNotSupported = errors.New("fetching archived files is not supported")
+)
+
+//archivedManifestKey is the key the manifest of every job archiving its files is uploaded to, whatever the key template
+func archivedManifestKey(jobID string) string {
+	return jobsPrefix + jobID + "/" + ManifestFileName
+}
+
+//CreateRollbackJob creates a job republishing the archived files of the given earlier successful job as the current export.
+//The job and its files are found by its archived manifest, so any job archived by this or another instance can be rolled back to.
+//The job is run like the requested ones, right away or once the queued jobs finished, if authorize approves its concept types
+func (fe *FullExporter) CreateRollbackJob(ctx context.Context, jobID, tid string, authorize Authorize) (Job, error) {
+	manifest, err := fe.fetchManifest(jobID, tid)
+	if err != nil {
+		return Job{}, err
+	}
+	if err := authorize.check(manifest.Concepts); err != nil {
+		return Job{}, err
+	}
+	var created event.Event
+	defer func() {
+		fe.publishEvent(created)
+	}()
+	fe.Lock()
+	defer fe.Unlock()
+	job := &Job{ID: "job_" + uuid.New(), Status: concept.STARTING, Concepts: manifest.Concepts, Options: optionsOf(manifest.Options), RollbackOf: jobID, rollbackFiles: manifest.Files}
+	if err := fe.enqueue(ctx, job, tid, true); err != nil {
+		return Job{}, err
+	}
+	created = event.Event{Type: event.JobCreated, JobID: job.ID, TransactionID: tid, Concepts: manifest.Concepts}
+	return fe.copyJob(job), nil
+}
+
+//fetchManifest reads the archived manifest of the job, checking that every file of the job can still be read from a key no later job overwrote
+func (fe *FullExporter) fetchManifest(jobID, tid string) (Manifest, error) {
+	if fe.Fetcher == nil {
+		return Manifest{}, ErrRollbackNotSupported
+	}
+	content, err := fe.Fetcher.Fetch(archivedManifestKey(jobID), tid)
+	if err == concept.ErrNotFound {
+		return Manifest{}, ErrJobNotFound
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("fetching the manifest of job %v failed: %w", jobID, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("manifest of job %v is unreadable: %w", jobID, err)
+	}
+	if manifest.JobID != jobID || len(manifest.Failed) != 0 || len(manifest.Files) == 0 {
+		return Manifest{}, ErrJobNotRollbackable
+	}
+	for _, f := range manifest.Files {
+		if f.Key == f.Name {
+			return Manifest{}, ErrJobNotRollbackable
+		}
+	}
+	return manifest, nil
+}
+
+//rollback fetches every archived file of the rolled back job and verifies its checksum before any of them is republished.
+//In atomic mode the files stay under their keys and only the latest manifest naming them is uploaded again
+func (fe *FullExporter) rollback(ctx context.Context, tid string) {
+	logEntry := fe.Log.WithTransactionID(tid)
+	fe.RLock()
+	files := fe.job.rollbackFiles
+	fe.RUnlock()
+
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+		content, err := fe.fetchArchived(f, tid)
+		if err != nil {
+			logEntry.Errorf("Fetching %v failed: %v", f.Key, err)
+			fe.setJobFailed(f.ExportType)
+			fe.setJobErrorMessage(fmt.Sprintf("%s %s", fe.job.ErrorMessage, err.Error()))
+			return
+		}
+		contents[i] = content
+	}
+
+	locate := locateByName
+	if fe.AtomicPublish {
+		locate = locateByKey
+	}
+	for i, f := range files {
+		fe.setJobProgress(f.ExportType)
+		if !fe.AtomicPublish {
+			err := fe.upload(ctx, contents[i], f.Name, f.ExportType, tid)
+			if err != nil {
+				logEntry.Errorf("Republishing %v failed: %v", f.Name, err)
+				fe.setJobFailed(f.ExportType)
+				fe.setJobErrorMessage(fmt.Sprintf("%s %s", fe.job.ErrorMessage, err.Error()))
+				return
+			}
+		}
+		fe.addJobFile(f)
+	}
+
+	fe.setJobEndTime()
+	if ctx.Err() != nil {
+		fe.setJobErrorMessage(fmt.Sprintf("%s %s", fe.job.ErrorMessage, ErrLeaseLost.Error()))
+		return
+	}
+	if fe.uploadDescriptors(ctx, latestKey, locate, tid) == nil {
+		fe.setJobPublished()
+	}
+}
+
+//fetchArchived reads the archived file from its key, checking it is still the file of the job archiving it
+func (fe *FullExporter) fetchArchived(f File, tid string) ([]byte, error) {
+	content, err := fe.Fetcher.Fetch(f.Key, tid)
+	if err == nil && checksum(content) != f.SHA256 {
+		err = fmt.Errorf("checksum of archived file %v does not match", f.Key)
+	}
+	return content, err
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
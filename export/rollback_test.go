@@ -0,0 +1,140 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockFetcher struct {
+	mock.Mock
+}
+
+func (m *mockFetcher) Fetch(fileName, tid string) ([]byte, error) {
+	args := m.Called(fileName, tid)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func newArchivingTestExporter(updater *mockUpdater, inquirer *mockInquirer, fetcher *mockFetcher) *FullExporter {
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC"))
+	exporter.KeyTemplate = "{type}/{jobId}.{ext}"
+	exporter.Fetcher = fetcher
+	return exporter
+}
+
+func runArchivedTestJob(t *testing.T, exporter *FullExporter, inquirer *mockInquirer, updater *mockUpdater, workers ...*concept.Worker) (string, []byte, []byte) {
+	if len(workers) == 0 {
+		workers = []*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"})}
+	}
+	inquirer.On("Inquire", []string{"Brand"}, concept.Options{}, "tid_1234").Return(workers).Once()
+	uploads := recordUploads(updater, "tid_1234")
+	job := runTestJob(t, exporter, []string{"Brand"}, concept.Options{})
+	return job.ID, uploads.contents["Brand/"+job.ID+".csv"], uploads.contents["jobs/"+job.ID+"/"+ManifestFileName]
+}
+
+func TestFullExporter_RollbackRepublishesArchivedFiles(t *testing.T) {
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	fetcher := new(mockFetcher)
+	sourceID, brandCsv, manifestJSON := runArchivedTestJob(t, newArchivingTestExporter(updater, inquirer, fetcher), inquirer, updater)
+	//the rolled back job is found by its archived manifest, not by the job history of the instance that ran it
+	exporter := newArchivingTestExporter(updater, inquirer, fetcher)
+
+	fetcher.On("Fetch", "jobs/"+sourceID+"/"+ManifestFileName, "tid_5678").Return(manifestJSON, nil)
+	fetcher.On("Fetch", "Brand/"+sourceID+".csv", "tid_5678").Return(brandCsv, nil)
+	var uploaded []string
+	updater.On("Upload", mock.Anything, mock.Anything, "tid_5678").Run(func(args mock.Arguments) {
+		uploaded = append(uploaded, args.String(1))
+	}).Return(nil)
+
+	job, err := exporter.CreateRollbackJob(context.Background(), sourceID, "tid_5678", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, sourceID, job.RollbackOf)
+	assert.Equal(t, []string{"Brand"}, job.Concepts)
+	waitForJob(t, exporter, job.ID)
+
+	assert.Equal(t, []string{"Brand.csv", DataPackageFileName, ManifestFileName}, uploaded)
+	current := exporter.GetCurrentJob()
+	assert.Equal(t, job.ID, current.ID)
+	assert.Equal(t, concept.FINISHED, current.Status)
+	assert.True(t, current.Published)
+	assert.Equal(t, "Brand/"+sourceID+".csv", current.Files[0].Key)
+	fetcher.AssertExpectations(t)
+}
+
+func TestFullExporter_RollbackPublishesAtomicallyByManifest(t *testing.T) {
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	fetcher := new(mockFetcher)
+	exporter := newArchivingTestExporter(updater, inquirer, fetcher)
+	exporter.AtomicPublish = true
+	sourceID, brandCsv, manifestJSON := runArchivedTestJob(t, exporter, inquirer, updater)
+
+	fetcher.On("Fetch", "jobs/"+sourceID+"/"+ManifestFileName, "tid_5678").Return(manifestJSON, nil)
+	fetcher.On("Fetch", "Brand/"+sourceID+".csv", "tid_5678").Return(brandCsv, nil)
+	var uploaded []string
+	var latestManifest []byte
+	updater.On("Upload", mock.Anything, mock.Anything, "tid_5678").Run(func(args mock.Arguments) {
+		uploaded = append(uploaded, args.String(1))
+		latestManifest = args.Get(0).([]byte)
+	}).Return(nil)
+
+	job, err := exporter.CreateRollbackJob(context.Background(), sourceID, "tid_5678", nil)
+	assert.NoError(t, err)
+	waitForJob(t, exporter, job.ID)
+
+	assert.Equal(t, []string{DataPackageFileName, ManifestFileName}, uploaded)
+	var manifest Manifest
+	assert.NoError(t, json.Unmarshal(latestManifest, &manifest))
+	assert.Equal(t, sourceID, manifest.RollbackOf)
+	assert.Equal(t, "Brand/"+sourceID+".csv", manifest.Files[0].Key)
+	assert.True(t, exporter.GetCurrentJob().Published)
+}
+
+func TestFullExporter_RollbackChecksumMismatch(t *testing.T) {
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	fetcher := new(mockFetcher)
+	exporter := newArchivingTestExporter(updater, inquirer, fetcher)
+	sourceID, _, manifestJSON := runArchivedTestJob(t, exporter, inquirer, updater)
+
+	fetcher.On("Fetch", "jobs/"+sourceID+"/"+ManifestFileName, "tid_5678").Return(manifestJSON, nil)
+	fetcher.On("Fetch", "Brand/"+sourceID+".csv", "tid_5678").Return([]byte("tampered"), nil)
+
+	job, err := exporter.CreateRollbackJob(context.Background(), sourceID, "tid_5678", nil)
+	assert.NoError(t, err)
+	waitForJob(t, exporter, job.ID)
+
+	current := exporter.GetCurrentJob()
+	assert.False(t, current.Published)
+	assert.Equal(t, []string{"Brand"}, current.Failed)
+	assert.Contains(t, current.ErrorMessage, "checksum of archived file")
+	updater.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, "tid_5678")
+}
+
+func TestFullExporter_CreateRollbackJobErrors(t *testing.T) {
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	fetcher := new(mockFetcher)
+
+	_, err := NewFullExporter(1, updater, inquirer, NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC")).CreateRollbackJob(context.Background(), "job_unknown", "tid_5678", nil)
+	assert.Equal(t, ErrRollbackNotSupported, err)
+
+	exporter := newArchivingTestExporter(updater, inquirer, fetcher)
+	fetcher.On("Fetch", "jobs/job_unknown/"+ManifestFileName, "tid_5678").Return([]byte(nil), concept.ErrNotFound)
+	_, err = exporter.CreateRollbackJob(context.Background(), "job_unknown", "tid_5678", nil)
+	assert.Equal(t, ErrJobNotFound, err)
+
+	failedID, _, manifestJSON := runArchivedTestJob(t, exporter, inquirer, updater, newFailingTestWorker("Brand", errors.New("Neo err")))
+	fetcher.On("Fetch", "jobs/"+failedID+"/"+ManifestFileName, "tid_5678").Return(manifestJSON, nil)
+	_, err = exporter.CreateRollbackJob(context.Background(), failedID, "tid_5678", nil)
+	assert.Equal(t, ErrJobNotRollbackable, err)
+	assert.Len(t, exporter.GetJobs(), 1)
+}
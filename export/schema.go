@@ -0,0 +1,141 @@
+package export
+
+import (
+	"strings"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+)
+
+const (
+	DataPackageFileName = "datapackage.json"
+	//SchemaVersion is the version of the layout of the exported files. It changes whenever a column is renamed or removed
+	SchemaVersion = "1"
+)
+
+//Field describes a column of the exported files, following the Frictionless Table Schema
+type Field struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Format      string `json:"format,omitempty"`
+	Delimiter   string `json:"delimiter,omitempty"`
+	ItemType    string `json:"itemType,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+//TableSchema is the Frictionless Table Schema of an exported file
+type TableSchema struct {
+	Fields     []Field  `json:"fields"`
+	PrimaryKey []string `json:"primaryKey,omitempty"`
+}
+
+//Resource is a Frictionless Data Resource describing an exported file
+type Resource struct {
+	Name      string      `json:"name"`
+	Path      string      `json:"path"`
+	Profile   string      `json:"profile"`
+	Format    string      `json:"format"`
+	MediaType string      `json:"mediatype"`
+	Encoding  string      `json:"encoding"`
+	Schema    TableSchema `json:"schema"`
+}
+
+//DataPackage is the Frictionless Data Package descriptor of the files exported together
+type DataPackage struct {
+	Profile   string     `json:"profile"`
+	Name      string     `json:"name"`
+	Version   string     `json:"version"`
+	Resources []Resource `json:"resources"`
+}
+
+var fields = map[string]Field{
+	"id":              {Name: "id", Type: "string", Format: "uri", Description: "Identifier of the canonical concept"},
+	"prefLabel":       {Name: "prefLabel", Type: "string", Description: "Preferred label of the canonical concept"},
+	"apiUrl":          {Name: "apiUrl", Type: "string", Format: "uri", Description: "URL of the canonical concept in the public APIs"},
+	"leiCode":         {Name: "leiCode", Type: "string", Description: "Legal Entity Identifier of the organisation"},
+	"factsetId":       {Name: "factsetId", Type: "list", Delimiter: ";", ItemType: "string", Description: "FactSet identifiers of the organisation"},
+	"FIGI":            {Name: "FIGI", Type: "list", Delimiter: ";", ItemType: "string", Description: "FIGI codes of the financial instruments issued by the organisation"},
+	"annotated":       {Name: "annotated", Type: "boolean", Description: "Whether any content annotates the concept"},
+	"contentUUID":     {Name: "contentUUID", Type: "string", Description: "UUID of the annotating content"},
+	"conceptId":       {Name: "conceptId", Type: "string", Format: "uri", Description: "Identifier of the canonical concept"},
+	"predicate":       {Name: "predicate", Type: "string", Description: "Predicate of the annotation"},
+	"conceptType":     {Name: "conceptType", Type: "string", Description: "Exported type of the canonical concept"},
+	"prefUUID":        {Name: "prefUUID", Type: "string", Description: "UUID of the canonical concept"},
+	"sourceUUID":      {Name: "sourceUUID", Type: "string", Description: "UUID of the source concept"},
+	"authority":       {Name: "authority", Type: "string", Description: "Authority of the source concept"},
+	"authorityValue":  {Name: "authorityValue", Type: "string", Description: "Identifier of the source concept within its authority"},
+	"sourceId":        {Name: "sourceId", Type: "string", Format: "uri", Description: "Identifier of the first canonical concept of the pair"},
+	"sourcePrefLabel": {Name: "sourcePrefLabel", Type: "string", Description: "Preferred label of the first canonical concept of the pair"},
+	"sourceType":      {Name: "sourceType", Type: "string", Description: "Exported type of the first canonical concept of the pair"},
+	"targetId":        {Name: "targetId", Type: "string", Format: "uri", Description: "Identifier of the second canonical concept of the pair"},
+	"targetPrefLabel": {Name: "targetPrefLabel", Type: "string", Description: "Preferred label of the second canonical concept of the pair"},
+	"targetType":      {Name: "targetType", Type: "string", Description: "Exported type of the second canonical concept of the pair"},
+	"rank":            {Name: "rank", Type: "integer", Description: "Rank of the concept by number of annotating content"},
+	"count":           {Name: "count", Type: "integer", Description: "Number of annotating content"},
+	"previousCount":   {Name: "previousCount", Type: "integer", Description: "Number of annotating content in the previous window"},
+	"growth":          {Name: "growth", Type: "number", Description: "Relative change of the number of annotating content since the previous window"},
+}
+
+func getHeader(exportType string, opts concept.Options) []string {
+	if opts.Kind == concept.TRENDING {
+		if opts.Compare {
+			return []string{"rank", "id", "prefLabel", "apiUrl", "count", "previousCount", "growth"}
+		}
+		return []string{"rank", "id", "prefLabel", "apiUrl", "count"}
+	}
+	switch exportType {
+	case concept.Annotations:
+		return []string{"contentUUID", "conceptId", "predicate", "conceptType"}
+	case concept.Concordance:
+		return []string{"conceptId", "prefUUID", "sourceUUID", "authority", "authorityValue", "conceptType"}
+	case string(concept.COOCCURRENCES):
+		return []string{"sourceId", "sourcePrefLabel", "sourceType", "targetId", "targetPrefLabel", "targetType", "count"}
+	}
+	header := []string{"id", "prefLabel", "apiUrl"}
+	if exportType == "Organisation" {
+		header = append(header, "leiCode", "factsetId", "FIGI")
+	}
+	if opts.IncludeUnannotated {
+		header = append(header, "annotated")
+	}
+	return header
+}
+
+func getPrimaryKey(exportType string, opts concept.Options) []string {
+	if opts.Kind == concept.TRENDING {
+		return []string{"id"}
+	}
+	switch exportType {
+	case concept.Annotations:
+		return []string{"contentUUID", "conceptId", "predicate"}
+	case concept.Concordance:
+		return []string{"sourceUUID"}
+	case string(concept.COOCCURRENCES):
+		return []string{"sourceId", "targetId"}
+	}
+	return []string{"id"}
+}
+
+func getTableSchema(exportType string, opts concept.Options) TableSchema {
+	var schemaFields []Field
+	for _, name := range getHeader(exportType, opts) {
+		schemaFields = append(schemaFields, fields[name])
+	}
+	return TableSchema{Fields: schemaFields, PrimaryKey: getPrimaryKey(exportType, opts)}
+}
+
+//DataPackage describes the files of the given export types rendered by the exporter
+func (e *CsvExporter) DataPackage(exportTypes []string, opts concept.Options) DataPackage {
+	resources := []Resource{}
+	for _, eType := range exportTypes {
+		resources = append(resources, Resource{
+			Name:      strings.ToLower(eType),
+			Path:      e.GetFileName(eType),
+			Profile:   "tabular-data-resource",
+			Format:    e.Format(),
+			MediaType: e.MediaType(),
+			Encoding:  "utf-8",
+			Schema:    getTableSchema(eType, opts),
+		})
+	}
+	return DataPackage{Profile: "tabular-data-package", Name: "concept-exporter", Version: SchemaVersion, Resources: resources}
+}
@@ -0,0 +1,32 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCsvExporter_DataPackageDescribesEveryColumn(t *testing.T) {
+	exporter := NewCsvExporter()
+	opts := concept.Options{ReadOptions: db.ReadOptions{IncludeUnannotated: true}, Annotations: true}
+
+	dataPackage := exporter.DataPackage(opts.ExportTypes([]string{"Organisation"}), opts)
+
+	assert.Equal(t, "tabular-data-package", dataPackage.Profile)
+	assert.Equal(t, 2, len(dataPackage.Resources))
+	for _, resource := range dataPackage.Resources {
+		var names []string
+		for _, f := range resource.Schema.Fields {
+			assert.NotEmpty(t, f.Type, "type of field %v", f.Name)
+			names = append(names, f.Name)
+		}
+		assert.Equal(t, getHeader(strings.TrimSuffix(resource.Path, ".csv"), opts), names)
+	}
+	organisation := dataPackage.Resources[0].Schema
+	assert.Equal(t, []string{"id"}, organisation.PrimaryKey)
+	assert.Equal(t, Field{Name: "factsetId", Type: "list", Delimiter: ";", ItemType: "string", Description: "FactSet identifiers of the organisation"}, organisation.Fields[4])
+	assert.Equal(t, "boolean", organisation.Fields[6].Type)
+}
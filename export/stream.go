@@ -0,0 +1,72 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+)
+
+//JSONLinesFormat renders every row as a JSON object on a line of its own
+const JSONLinesFormat = "jsonl"
+
+//StreamWriter renders the records of an export type straight to a writer, as the rows of its file or as JSON lines
+//keyed by the same columns, instead of buffering the whole file like the CsvExporter
+type StreamWriter struct {
+	header  []string
+	csv     *csv.Writer
+	encoder *json.Encoder
+}
+
+//NewStreamWriter returns a StreamWriter of the export type in the given format, csv or jsonl
+func NewStreamWriter(w io.Writer, exportType string, opts concept.Options, format string) (*StreamWriter, error) {
+	s := &StreamWriter{header: getHeader(exportType, opts)}
+	switch format {
+	case CSVFormat:
+		s.csv = csv.NewWriter(w)
+	case JSONLinesFormat:
+		s.encoder = json.NewEncoder(w)
+	default:
+		return nil, fmt.Errorf("unsupported stream format %v", format)
+	}
+	return s, nil
+}
+
+//WriteHeader writes the header row of the CSV format. JSON lines have no header
+func (s *StreamWriter) WriteHeader() error {
+	if s.csv == nil {
+		return nil
+	}
+	return s.csv.Write(s.header)
+}
+
+func (s *StreamWriter) Write(r db.Record) error {
+	if s.csv == nil {
+		return s.encoder.Encode(row(s.header, r))
+	}
+	rec := make([]string, len(s.header))
+	for i, column := range s.header {
+		rec[i] = r.Value(column)
+	}
+	return s.csv.Write(rec)
+}
+
+//Flush writes the buffered CSV rows
+func (s *StreamWriter) Flush() error {
+	if s.csv == nil {
+		return nil
+	}
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+func row(header []string, r db.Record) map[string]string {
+	row := make(map[string]string, len(header))
+	for _, column := range header {
+		row[column] = r.Value(column)
+	}
+	return row
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamWriter(t *testing.T) {
+	records := []db.Record{
+		db.Concept{Id: "http://api.ft.com/things/brand1", PrefLabel: "Brand, One", ApiUrl: "http://api.ft.com/brands/brand1"},
+		db.Concept{Id: "http://api.ft.com/things/brand2", PrefLabel: "Brand Two", ApiUrl: "http://api.ft.com/brands/brand2"},
+	}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: CSVFormat,
+			expected: "id,prefLabel,apiUrl\n" +
+				"http://api.ft.com/things/brand1,\"Brand, One\",http://api.ft.com/brands/brand1\n" +
+				"http://api.ft.com/things/brand2,Brand Two,http://api.ft.com/brands/brand2\n",
+		},
+		{
+			format: JSONLinesFormat,
+			expected: `{"apiUrl":"http://api.ft.com/brands/brand1","id":"http://api.ft.com/things/brand1","prefLabel":"Brand, One"}` + "\n" +
+				`{"apiUrl":"http://api.ft.com/brands/brand2","id":"http://api.ft.com/things/brand2","prefLabel":"Brand Two"}` + "\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			w, err := NewStreamWriter(buffer, "Brand", concept.Options{}, test.format)
+			assert.NoError(t, err)
+			assert.NoError(t, w.WriteHeader())
+			for _, r := range records {
+				assert.NoError(t, w.Write(r))
+			}
+			assert.NoError(t, w.Flush())
+			assert.Equal(t, test.expected, buffer.String())
+		})
+	}
+
+	_, err := NewStreamWriter(new(bytes.Buffer), "Brand", concept.Options{}, JSONFormat)
+	assert.EqualError(t, err, "unsupported stream format json")
+}
@@ -1,12 +1,18 @@
 package export
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"sync"
+	"time"
 
 	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/event"
+	"github.com/Financial-Times/concept-exporter/monitoring"
+	"github.com/Financial-Times/concept-exporter/tracing"
 	logger "github.com/Financial-Times/go-logger/v2"
-	"github.com/pborman/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Job struct {
@@ -19,20 +25,85 @@ type Job struct {
 	Failed       []string          `json:"Failed,omitempty"`
 	Status       concept.State     `json:"Status"`
 	ErrorMessage string            `json:"ErrorMessage,omitempty"`
+	Options      *concept.Options  `json:"Options,omitempty"`
+	StartTime    *time.Time        `json:"StartTime,omitempty"`
+	EndTime      *time.Time        `json:"EndTime,omitempty"`
+	Files        []File            `json:"Files,omitempty"`
+	Published    bool              `json:"Published,omitempty"`
+	RollbackOf   string            `json:"RollbackOf,omitempty"`
+	Callbacks    []string          `json:"Callbacks,omitempty"`
+	RetryOf      string            `json:"RetryOf,omitempty"`
+	Position     int               `json:"Position,omitempty"`
+	tid          string
+	//ctx holds the trace of the request creating the job, without its cancellation
+	ctx context.Context
+	//rollbackFiles are the archived files of the job rolled back to
+	rollbackFiles []File
+	//retriedFiles are the files of the retried job which did not fail, published again with the files of the retry
+	retriedFiles []File
+}
+
+//optionsOf leaves the options out of the job when they are the defaults of a plain export
+func optionsOf(opts concept.Options) *concept.Options {
+	if reflect.DeepEqual(opts, concept.Options{}) {
+		return nil
+	}
+	return &opts
+}
+
+//options returns the options of the job, the defaults when it has none
+func (j *Job) options() concept.Options {
+	if j.Options == nil {
+		return concept.Options{}
+	}
+	return *j.Options
+}
+
+const (
+	defaultHistorySize         = 50
+	defaultQueueSize           = 10
+	defaultLeaderCheckInterval = time.Second
+)
+
+//Leader tells whether this replica runs the export jobs
+type Leader interface {
+	IsLeader() bool
 }
 
 type FullExporter struct {
 	sync.RWMutex
 	job                   *Job
+	history               []*Job
+	HistorySize           int
+	queue                 []*Job
+	QueueSize             int
+	running               bool
 	NrOfConcurrentWorkers int
 	Updater               concept.Updater
+	Fetcher               concept.Fetcher
+	Notifier              *Notifier
+	Events                event.Publisher
 	Inquirer              concept.Inquirer
+	Counter               concept.Counter
+	Preflights            []Preflight
 	Exporter              *CsvExporter
 	Log                   *logger.UPPLogger
+	//AtomicPublish uploads the files of a job only under its own keys and publishes them once every export type succeeded, by uploading the latest manifest naming these keys
+	AtomicPublish bool
+	//Retries is how many times the failed types of a job are exported again before the job finishes
+	Retries int
+	//KeyTemplate archives the files of every job under their own keys, next to the latest copy under their well-known names
+	KeyTemplate KeyTemplate
+	//Leader runs the jobs only while this replica is the leader, the running job being cancelled once it isn't anymore, checked every LeaderCheckInterval
+	Leader              Leader
+	LeaderCheckInterval time.Duration
 }
 
 func NewFullExporter(nrOfWorkers int, exporter concept.Updater, inquirer concept.Inquirer, csvExporter *CsvExporter, log *logger.UPPLogger) *FullExporter {
 	return &FullExporter{
+		HistorySize:           defaultHistorySize,
+		QueueSize:             defaultQueueSize,
+		LeaderCheckInterval:   defaultLeaderCheckInterval,
 		NrOfConcurrentWorkers: nrOfWorkers,
 		Updater:               exporter,
 		Inquirer:              inquirer,
@@ -41,13 +112,11 @@ func NewFullExporter(nrOfWorkers int, exporter concept.Updater, inquirer concept
 	}
 }
 
+//IsRunningJob tells whether a job is starting, running or queued
 func (fe *FullExporter) IsRunningJob() bool {
-	fe.Lock()
-	defer fe.Unlock()
-	if fe.job == nil {
-		return false
-	}
-	return fe.job.Status == concept.RUNNING
+	fe.RLock()
+	defer fe.RUnlock()
+	return !fe.idle()
 }
 
 func (fe *FullExporter) GetCurrentJob() Job {
@@ -59,9 +128,69 @@ func (fe *FullExporter) GetCurrentJob() Job {
 	return fe.getJob()
 }
 
+//GetJob returns the current job or a job of the history with the given ID
+func (fe *FullExporter) GetJob(id string) (Job, bool) {
+	fe.Lock()
+	defer fe.Unlock()
+	job := fe.findJob(id)
+	if job == nil {
+		return Job{}, false
+	}
+	return fe.copyJob(job), true
+}
+
+//GetJobs returns the jobs of the history followed by the current job and the queued ones
+func (fe *FullExporter) GetJobs() []Job {
+	fe.Lock()
+	defer fe.Unlock()
+	jobs := []Job{}
+	for _, job := range fe.history {
+		jobs = append(jobs, copyJob(job, 0))
+	}
+	if fe.job != nil {
+		jobs = append(jobs, fe.getJob())
+	}
+	for _, job := range fe.queue {
+		jobs = append(jobs, fe.copyJob(job))
+	}
+	return jobs
+}
+
+func (fe *FullExporter) findJob(id string) *Job {
+	if fe.job != nil && fe.job.ID == id {
+		return fe.job
+	}
+	for _, job := range fe.history {
+		if job.ID == id {
+			return job
+		}
+	}
+	for _, job := range fe.queue {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
 func (fe *FullExporter) getJob() Job {
+	return copyJob(fe.job, 0)
+}
+
+//copyJob copies the job with its position in the queue
+func (fe *FullExporter) copyJob(job *Job) Job {
+	position := 0
+	for i, queued := range fe.queue {
+		if queued == job {
+			position = i + 1
+		}
+	}
+	return copyJob(job, position)
+}
+
+func copyJob(job *Job, position int) Job {
 	var workers []*concept.Worker
-	for _, w := range fe.job.Workers {
+	for _, w := range job.Workers {
 		workers = append(workers, &concept.Worker{
 			ConceptType:  w.ConceptType,
 			Progress:     w.Progress,
@@ -71,21 +200,34 @@ func (fe *FullExporter) getJob() Job {
 		})
 	}
 	return Job{
-		ID:           fe.job.ID,
-		Status:       fe.job.Status,
-		ErrorMessage: fe.job.ErrorMessage,
-		Concepts:     fe.job.Concepts,
-		Progress:     fe.job.Progress,
-		Failed:       fe.job.Failed,
+		ID:           job.ID,
+		Status:       job.Status,
+		ErrorMessage: job.ErrorMessage,
+		Concepts:     job.Concepts,
+		Progress:     job.Progress,
+		Failed:       job.Failed,
 		Workers:      workers,
+		Options:      job.Options,
+		StartTime:    job.StartTime,
+		EndTime:      job.EndTime,
+		Files:        job.Files,
+		Published:    job.Published,
+		RollbackOf:   job.RollbackOf,
+		Callbacks:    job.Callbacks,
+		RetryOf:      job.RetryOf,
+		Position:     position,
 	}
 }
 
-func (fe *FullExporter) CreateJob(candidates []string, errMsg string) Job {
-	fe.Lock()
-	defer fe.Unlock()
-	fe.job = &Job{ID: "job_" + uuid.New(), NrWorker: fe.NrOfConcurrentWorkers, Status: concept.STARTING, Concepts: candidates, ErrorMessage: errMsg}
-	return fe.getJob()
+//setJob makes the given job the current one, keeping the previous one in the history
+func (fe *FullExporter) setJob(job *Job) {
+	if fe.job != nil {
+		fe.history = append(fe.history, fe.job)
+		if len(fe.history) > fe.HistorySize {
+			fe.history = fe.history[len(fe.history)-fe.HistorySize:]
+		}
+	}
+	fe.job = job
 }
 
 func (fe *FullExporter) setJobStatus(state concept.State) {
@@ -94,12 +236,56 @@ func (fe *FullExporter) setJobStatus(state concept.State) {
 	fe.job.Status = state
 }
 
+func (fe *FullExporter) setJobStartTime() {
+	fe.Lock()
+	defer fe.Unlock()
+	now := time.Now().UTC()
+	fe.job.StartTime = &now
+}
+
+func (fe *FullExporter) setJobEndTime() {
+	fe.Lock()
+	defer fe.Unlock()
+	if fe.job.EndTime != nil {
+		return
+	}
+	now := time.Now().UTC()
+	fe.job.EndTime = &now
+}
+
+func (fe *FullExporter) setJobPublished() {
+	fe.Lock()
+	defer fe.Unlock()
+	fe.job.Published = true
+}
+
+func (fe *FullExporter) addJobFile(file File) {
+	fe.Lock()
+	defer fe.Unlock()
+	fe.job.Files = append(fe.job.Files, file)
+}
+
 func (fe *FullExporter) setJobWorkers(workers []*concept.Worker) {
 	fe.Lock()
 	defer fe.Unlock()
 	fe.job.Workers = workers
 }
 
+func (fe *FullExporter) addJobWorkers(workers []*concept.Worker) {
+	fe.Lock()
+	defer fe.Unlock()
+	fe.job.Workers = append(fe.job.Workers, workers...)
+}
+
+//takeJobFailed clears the failed types of the job, returning them
+func (fe *FullExporter) takeJobFailed() []string {
+	fe.Lock()
+	defer fe.Unlock()
+	failed := fe.job.Failed
+	fe.job.Failed = nil
+	return failed
+}
+
 func (fe *FullExporter) setJobErrorMessage(msg string) {
 	fe.Lock()
 	defer fe.Unlock()
@@ -118,34 +304,132 @@ func (fe *FullExporter) setJobFailed(cType string) {
 	fe.job.Failed = append(fe.job.Failed, cType)
 }
 
-func (fe *FullExporter) RunFullExport(tid string) {
+//RunFullExport runs the current job, tracing it as part of the trace of the given context
+func (fe *FullExporter) RunFullExport(ctx context.Context, tid string) {
 	logEntry := fe.Log.WithTransactionID(tid)
-	if fe.job == nil || fe.job.Status != concept.STARTING {
+	if !fe.start() {
 		logEntry.Error("No job to be run")
 		return
 	}
 
+	defer fe.runNext()
+
 	logEntry.Infof("Job started: %v", fe.job.ID)
+	ctx, span := tracing.StartFrom(ctx, tid, "FullExporter.RunFullExport", attribute.String("job_id", fe.job.ID))
+	defer span.End()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go fe.watchLeader(ctx, cancel, tid)
 	fe.setJobStatus(concept.RUNNING)
+	fe.setJobStartTime()
 	defer func() {
+		fe.setJobEndTime()
 		fe.setJobStatus(concept.FINISHED)
+		monitoring.JobDuration.Observe(fe.job.EndTime.Sub(*fe.job.StartTime).Seconds())
 		logEntry.Infof("Finished job %v with failed concept(s): %v, progress: %v", fe.job.ID, fe.job.Failed, fe.job.Progress)
+		fe.Lock()
+		job := fe.getJob()
+		fe.Unlock()
+		var keys []string
+		for _, f := range job.Files {
+			keys = append(keys, f.Key)
+		}
+		fe.publishEvent(event.Event{Type: event.JobFinished, JobID: job.ID, TransactionID: tid, Concepts: job.Concepts, Keys: keys, Failed: job.Failed, Published: job.Published, ErrorMessage: job.ErrorMessage})
+		if fe.Notifier != nil {
+			//the callbacks are notified off the job, so slow or unreachable ones don't hold the queued jobs back
+			go fe.Notifier.Notify(&job, tid)
+		}
 	}()
 
-	err := fe.Exporter.Prepare(fe.job.Concepts)
+	if fe.job.RollbackOf != "" {
+		fe.rollback(ctx, tid)
+		return
+	}
+
+	err := fe.Exporter.Prepare(fe.job.Concepts, fe.job.options())
 	if err != nil {
 		logEntry.Errorf("Preparing CSV writer failed: %v", err.Error())
 		fe.setJobErrorMessage(fmt.Sprintf("%s %s", fe.job.ErrorMessage, err.Error()))
 		return
 	}
 
-	fe.setJobWorkers(fe.Inquirer.Inquire(fe.job.Concepts, tid))
+	fe.setJobWorkers(fe.Inquirer.Inquire(ctx, fe.job.Concepts, fe.job.options(), tid))
 
 	for _, worker := range fe.job.Workers {
-		fe.runExport(worker, tid)
+		fe.runExport(ctx, worker, tid)
+	}
+	fe.retryFailed(ctx, tid)
+	fe.addRetriedFiles(ctx, tid)
+
+	fe.setJobEndTime()
+	if ctx.Err() != nil {
+		fe.setJobErrorMessage(fmt.Sprintf("%s %s", fe.job.ErrorMessage, ErrLeaseLost.Error()))
+		return
+	}
+	fe.publish(ctx, tid)
+}
+
+//isLeader tells whether this replica runs the jobs, always when there's a single replica
+func (fe *FullExporter) isLeader() bool {
+	return fe.Leader == nil || fe.Leader.IsLeader()
+}
+
+//watchLeader cancels the job once this replica isn't the leader anymore, so it stops before another replica runs jobs
+func (fe *FullExporter) watchLeader(ctx context.Context, cancel context.CancelFunc, tid string) {
+	if fe.Leader == nil {
+		return
+	}
+	ticker := time.NewTicker(fe.LeaderCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !fe.Leader.IsLeader() {
+				fe.Log.WithTransactionID(tid).Warnf("Cancelling job %v as the replica lost the lease", fe.job.ID)
+				cancel()
+				return
+			}
+		}
 	}
 }
 
+//retryFailed exports the failed types of the job again, up to Retries times
+func (fe *FullExporter) retryFailed(ctx context.Context, tid string) {
+	for attempt := 1; attempt <= fe.Retries && len(fe.job.Failed) != 0 && ctx.Err() == nil; attempt++ {
+		failed := fe.takeJobFailed()
+		fe.Log.WithTransactionID(tid).Warnf("Retrying %v of job %v, attempt %d of %d", failed, fe.job.ID, attempt, fe.Retries)
+		opts := fe.job.options()
+		opts.Types = failed
+		for _, exportType := range failed {
+			if err := fe.Exporter.Reset(exportType, fe.job.options()); err != nil {
+				for _, t := range failed {
+					fe.setJobFailed(t)
+				}
+				fe.setJobErrorMessage(fmt.Sprintf("%s %s", fe.job.ErrorMessage, err.Error()))
+				return
+			}
+		}
+		workers := fe.Inquirer.Inquire(ctx, fe.job.Concepts, opts, tid)
+		fe.addJobWorkers(workers)
+		for _, worker := range workers {
+			fe.runExport(ctx, worker, tid)
+		}
+	}
+}
+
+//start marks the exporter as running the current job, from its start until everything after it finished, e.g. its notifications
+func (fe *FullExporter) start() bool {
+	fe.Lock()
+	defer fe.Unlock()
+	if fe.job == nil || fe.job.Status != concept.STARTING || fe.running {
+		return false
+	}
+	fe.running = true
+	return true
+}
+
 func (fe *FullExporter) setWorkerState(worker *concept.Worker, state concept.State) {
 	fe.Lock()
 	defer fe.Unlock()
@@ -164,39 +448,101 @@ func (fe *FullExporter) incWorkerProgress(worker *concept.Worker) {
 	worker.Progress++
 }
 
-func (fe *FullExporter) runExport(worker *concept.Worker, tid string) {
+func (fe *FullExporter) runExport(ctx context.Context, worker *concept.Worker, tid string) {
+	ctx, span := tracing.StartFrom(ctx, tid, "FullExporter.runExport", attribute.String("export_type", worker.ConceptType))
 	fe.setWorkerState(worker, concept.RUNNING)
 	defer func() {
 		fe.setWorkerState(worker, concept.FINISHED)
+		span.SetAttributes(attribute.Int("rows", worker.Progress))
+		span.End()
 	}()
 	fe.setJobProgress(worker.ConceptType)
+	fe.publishEvent(event.Event{Type: event.WorkerStarted, JobID: fe.job.ID, TransactionID: tid, ConceptType: worker.ConceptType})
+	//rows counts the records written to the file, the progress counting the failed writes too
+	rows := 0
 	for {
+		if ctx.Err() != nil {
+			fe.failWorker(worker, ErrLeaseLost, tid)
+			return
+		}
 		select {
-		case c, ok := <-worker.ConceptCh:
+		case <-ctx.Done():
+			fe.failWorker(worker, ErrLeaseLost, tid)
+			return
+		case r, ok := <-worker.RecordCh:
 			if !ok {
-				err := fe.Updater.Upload(fe.Exporter.GetBytes(worker.ConceptType), fe.Exporter.GetFileName(worker.ConceptType), tid)
+				content := fe.Exporter.GetBytes(worker.ConceptType)
+				fileName := fe.Exporter.GetFileName(worker.ConceptType)
+				key := fe.objectKey(fileName, worker.ConceptType)
+				err := fe.upload(ctx, content, key, worker.ConceptType, tid)
+				if err == nil && !fe.AtomicPublish && key != fileName {
+					err = fe.upload(ctx, content, fileName, worker.ConceptType, tid)
+				}
 				if err != nil {
 					fe.Log.WithTransactionID(tid).Errorf("Upload to S3 Writer failed: %v", err)
-					fe.setJobFailed(worker.ConceptType)
-					fe.setWorkerErrorMessage(worker, fmt.Sprintf("%s %s", worker.ErrorMessage, err.Error()))
+					fe.failWorker(worker, err, tid)
+					return
 				}
-
+				fe.addJobFile(newFile(fileName, key, worker.ConceptType, rows, content))
+				if !fe.AtomicPublish {
+					//the file is published under its well-known name, atomic exports being published by their manifest
+					monitoring.LastSuccess.WithLabelValues(worker.ConceptType).SetToCurrentTime()
+				}
+				fe.publishEvent(event.Event{Type: event.WorkerFinished, JobID: fe.job.ID, TransactionID: tid, ConceptType: worker.ConceptType, Count: worker.Progress, Key: key})
 				return
 			}
 			fe.incWorkerProgress(worker)
-			err := fe.Exporter.Write(c, worker.ConceptType, tid)
+			monitoring.RowsRead.WithLabelValues(worker.ConceptType).Inc()
+			err := fe.Exporter.Write(r, worker.ConceptType, tid)
 			if err != nil {
 				fe.Log.WithTransactionID(tid).WithError(err).Warn("CSV exporter writing failed")
+				monitoring.RowsFailed.WithLabelValues(worker.ConceptType).Inc()
+				continue
 			}
+			rows++
+			monitoring.RowsWritten.WithLabelValues(worker.ConceptType).Inc()
 		case err, ok := <-worker.Errch:
 			if !ok {
 				//channel closed
 				return
 			}
-			fe.setJobFailed(worker.ConceptType)
-			fe.setWorkerErrorMessage(worker, fmt.Sprintf("%s %s", worker.ErrorMessage, err.Error()))
+			fe.failWorker(worker, err, tid)
 			return
 		}
 	}
 
 }
+
+func (fe *FullExporter) failWorker(worker *concept.Worker, err error, tid string) {
+	fe.setJobFailed(worker.ConceptType)
+	fe.setWorkerErrorMessage(worker, fmt.Sprintf("%s %s", worker.ErrorMessage, err.Error()))
+	fe.publishWorkerFailed(worker, err, tid)
+}
+
+//upload uploads the content to the S3 writer, measuring the upload for the given export type
+func (fe *FullExporter) upload(ctx context.Context, content []byte, key, exportType, tid string) error {
+	start := time.Now()
+	err := fe.Updater.Upload(ctx, content, key, tid)
+	monitoring.ObserveSince(monitoring.UploadDuration.WithLabelValues(exportType), start)
+	if err != nil {
+		monitoring.UploadFailures.WithLabelValues(exportType).Inc()
+		return err
+	}
+	monitoring.UploadBytes.WithLabelValues(exportType).Add(float64(len(content)))
+	return nil
+}
+
+func (fe *FullExporter) publishWorkerFailed(worker *concept.Worker, err error, tid string) {
+	fe.publishEvent(event.Event{Type: event.WorkerFailed, JobID: fe.job.ID, TransactionID: tid, ConceptType: worker.ConceptType, Count: worker.Progress, ErrorMessage: err.Error()})
+}
+
+//publishEvent publishes the event when a publisher is configured. A failing publisher never fails the job
+func (fe *FullExporter) publishEvent(e event.Event) {
+	if fe.Events == nil || e.Type == "" {
+		return
+	}
+	e.Time = time.Now().UTC()
+	if err := fe.Events.Publish(e); err != nil {
+		fe.Log.WithTransactionID(e.TransactionID).WithError(err).Warnf("Publishing %v event of job %v failed", e.Type, e.JobID)
+	}
+}
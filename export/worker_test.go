@@ -0,0 +1,287 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/concept-exporter/event"
+	"github.com/Financial-Times/concept-exporter/monitoring"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockUpdater struct {
+	mock.Mock
+}
+
+func (m *mockUpdater) Upload(_ context.Context, content []byte, fileName, tid string) error {
+	args := m.Called(content, fileName, tid)
+	return args.Error(0)
+}
+
+//uploadRecorder records the uploads of a transaction, for the tests checking files uploaded under the keys of a job
+type uploadRecorder struct {
+	sync.Mutex
+	names    []string
+	contents map[string][]byte
+}
+
+func recordUploads(updater *mockUpdater, tid string) *uploadRecorder {
+	r := &uploadRecorder{contents: map[string][]byte{}}
+	updater.On("Upload", mock.Anything, mock.Anything, tid).Run(func(args mock.Arguments) {
+		r.Lock()
+		defer r.Unlock()
+		r.names = append(r.names, args.String(1))
+		r.contents[args.String(1)] = args.Get(0).([]byte)
+	}).Return(nil)
+	return r
+}
+
+type mockInquirer struct {
+	mock.Mock
+}
+
+func (m *mockInquirer) Inquire(_ context.Context, candidates []string, opts concept.Options, tid string) []*concept.Worker {
+	args := m.Called(candidates, opts, tid)
+	return args.Get(0).([]*concept.Worker)
+}
+
+func newTestWorker(cType string, records ...db.Record) *concept.Worker {
+	worker := &concept.Worker{ConceptType: cType, RecordCh: make(chan db.Record, len(records)), Errch: make(chan error, 2), Status: concept.STARTING}
+	for _, r := range records {
+		worker.RecordCh <- r
+	}
+	close(worker.RecordCh)
+	return worker
+}
+
+func newFailingTestWorker(cType string, err error) *concept.Worker {
+	worker := &concept.Worker{ConceptType: cType, RecordCh: make(chan db.Record), Errch: make(chan error, 2), Status: concept.STARTING}
+	worker.Errch <- err
+	return worker
+}
+
+func TestFullExporter_RunFullExportUploadsManifest(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+
+	brand := db.Concept{Id: "http://api.ft.com/things/brand", PrefLabel: "Brand", ApiUrl: "http://api.ft.com/brands/brand"}
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", brand), newFailingTestWorker("Topic", errors.New("Neo err"))})
+	var brandCsv, manifestJSON []byte
+	updater.On("Upload", mock.Anything, "Brand.csv", "tid_1234").Run(func(args mock.Arguments) {
+		brandCsv = args.Get(0).([]byte)
+	}).Return(nil)
+	updater.On("Upload", mock.Anything, ManifestFileName, "tid_1234").Run(func(args mock.Arguments) {
+		manifestJSON = args.Get(0).([]byte)
+	}).Return(nil)
+	var dataPackageJSON []byte
+	updater.On("Upload", mock.Anything, DataPackageFileName, "tid_1234").Run(func(args mock.Arguments) {
+		dataPackageJSON = args.Get(0).([]byte)
+	}).Return(nil)
+
+	job := runTestJob(t, exporter, []string{"Brand", "Topic"}, concept.Options{})
+
+	var manifest Manifest
+	assert.NoError(t, json.Unmarshal(manifestJSON, &manifest))
+	assert.Equal(t, job.ID, manifest.JobID)
+	assert.Equal(t, []string{"Brand", "Topic"}, manifest.Concepts)
+	assert.Equal(t, "csv", manifest.Format)
+	assert.Equal(t, SchemaVersion, manifest.SchemaVersion)
+	assert.Equal(t, []string{"Topic"}, manifest.Failed)
+	assert.False(t, manifest.StartTime.IsZero())
+	assert.False(t, manifest.EndTime.Before(manifest.StartTime))
+	assert.Equal(t, []File{newFile("Brand.csv", "Brand.csv", "Brand", 1, brandCsv)}, manifest.Files)
+	assert.Equal(t, "id,prefLabel,apiUrl\nhttp://api.ft.com/things/brand,Brand,http://api.ft.com/brands/brand\n", string(brandCsv))
+	assert.Equal(t, concept.FINISHED, exporter.GetCurrentJob().Status)
+
+	var dataPackage DataPackage
+	assert.NoError(t, json.Unmarshal(dataPackageJSON, &dataPackage))
+	assert.Equal(t, 1, len(dataPackage.Resources))
+	assert.Equal(t, "brand", dataPackage.Resources[0].Name)
+	assert.Equal(t, "Brand.csv", dataPackage.Resources[0].Path)
+	assert.Equal(t, []string{"id"}, dataPackage.Resources[0].Schema.PrimaryKey)
+	updater.AssertExpectations(t)
+	inquirer.AssertExpectations(t)
+}
+
+func TestFullExporter_RunFullExportCountsWrittenRows(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+
+	inquirer.On("Inquire", []string{"Brand"}, concept.Options{}, "tid_1234").Run(func(args mock.Arguments) {
+		//an invalid delimiter fails every write of a record
+		exporter.Exporter.Writer["Brand"].Writer.Comma = '"'
+	}).Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand1"}, db.Concept{Id: "brand2"})})
+	var manifestJSON []byte
+	updater.On("Upload", mock.Anything, ManifestFileName, "tid_1234").Run(func(args mock.Arguments) {
+		manifestJSON = args.Get(0).([]byte)
+	}).Return(nil)
+	updater.On("Upload", mock.Anything, mock.Anything, "tid_1234").Return(nil)
+
+	runTestJob(t, exporter, []string{"Brand"}, concept.Options{})
+
+	var manifest Manifest
+	assert.NoError(t, json.Unmarshal(manifestJSON, &manifest))
+	assert.Equal(t, 1, len(manifest.Files))
+	assert.Equal(t, 0, manifest.Files[0].Rows)
+	assert.Equal(t, 2, exporter.GetCurrentJob().Workers[0].Progress)
+}
+
+func TestFullExporter_RunFullExportWithFailingUpload(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+
+	inquirer.On("Inquire", []string{"Brand"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"})})
+	updater.On("Upload", mock.Anything, "Brand.csv", "tid_1234").Return(errors.New("S3 err"))
+	updater.On("Upload", mock.Anything, ManifestFileName, "tid_1234").Return(nil)
+	updater.On("Upload", mock.Anything, DataPackageFileName, "tid_1234").Return(nil)
+
+	runTestJob(t, exporter, []string{"Brand"}, concept.Options{})
+
+	job := exporter.GetCurrentJob()
+	assert.Equal(t, []string{"Brand"}, job.Failed)
+	assert.Empty(t, job.Files)
+	updater.AssertExpectations(t)
+}
+
+func TestFullExporter_RunFullExportPublishesAtomically(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+	exporter.AtomicPublish = true
+
+	inquirer.On("Inquire", []string{"Brand"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"})})
+	uploads := recordUploads(updater, "tid_1234")
+
+	job := runTestJob(t, exporter, []string{"Brand"}, concept.Options{})
+
+	jobKey := "jobs/" + job.ID + "/"
+	assert.Equal(t, []string{jobKey + "Brand.csv", jobKey + DataPackageFileName, jobKey + ManifestFileName, DataPackageFileName, ManifestFileName}, uploads.names)
+	assert.True(t, exporter.GetCurrentJob().Published)
+	var manifest Manifest
+	assert.NoError(t, json.Unmarshal(uploads.contents[ManifestFileName], &manifest))
+	assert.Equal(t, jobKey+"Brand.csv", manifest.Files[0].Key)
+	var dataPackage DataPackage
+	assert.NoError(t, json.Unmarshal(uploads.contents[DataPackageFileName], &dataPackage))
+	assert.Equal(t, jobKey+"Brand.csv", dataPackage.Resources[0].Path)
+	updater.AssertNotCalled(t, "Upload", mock.Anything, "Brand.csv", "tid_1234")
+}
+
+func TestFullExporter_RunFullExportDoesNotPublishPartialExport(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+	exporter.AtomicPublish = true
+
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"}), newFailingTestWorker("Topic", errors.New("Neo err"))})
+	uploads := recordUploads(updater, "tid_1234")
+
+	lastSuccess := testutil.ToFloat64(monitoring.LastSuccess.WithLabelValues("Brand"))
+
+	job := runTestJob(t, exporter, []string{"Brand", "Topic"}, concept.Options{})
+
+	jobKey := "jobs/" + job.ID + "/"
+	assert.Equal(t, []string{jobKey + "Brand.csv", jobKey + DataPackageFileName, jobKey + ManifestFileName}, uploads.names)
+	assert.False(t, exporter.GetCurrentJob().Published)
+	assert.Contains(t, exporter.GetCurrentJob().ErrorMessage, "unpublished")
+	assert.Equal(t, lastSuccess, testutil.ToFloat64(monitoring.LastSuccess.WithLabelValues("Brand")), "the file of Brand is left unpublished")
+	updater.AssertNotCalled(t, "Upload", mock.Anything, ManifestFileName, "tid_1234")
+}
+
+func TestFullExporter_RunFullExportPublishesEvents(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	publisher := &event.MemoryPublisher{}
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+	exporter.Events = publisher
+
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand"}), newFailingTestWorker("Topic", errors.New("Neo err"))})
+	updater.On("Upload", mock.Anything, mock.Anything, "tid_1234").Return(nil)
+
+	job := runTestJob(t, exporter, []string{"Brand", "Topic"}, concept.Options{})
+
+	events := publisher.Events()
+	var types []event.Type
+	for _, e := range events {
+		assert.Equal(t, job.ID, e.JobID)
+		assert.False(t, e.Time.IsZero())
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []event.Type{event.JobCreated, event.WorkerStarted, event.WorkerFinished, event.WorkerStarted, event.WorkerFailed, event.JobFinished}, types)
+	assert.Equal(t, "Brand", events[2].ConceptType)
+	assert.Equal(t, 1, events[2].Count)
+	assert.Equal(t, "Brand.csv", events[2].Key)
+	assert.Equal(t, "Topic", events[4].ConceptType)
+	assert.Equal(t, "Neo err", events[4].ErrorMessage)
+	assert.Equal(t, []string{"Brand.csv"}, events[5].Keys)
+	assert.Equal(t, []string{"Topic"}, events[5].Failed)
+	assert.False(t, events[5].Published)
+}
+
+func TestFullExporter_RunFullExportRecordsMetrics(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), log)
+
+	rowsRead := testutil.ToFloat64(monitoring.RowsRead.WithLabelValues("Brand"))
+	rowsWritten := testutil.ToFloat64(monitoring.RowsWritten.WithLabelValues("Brand"))
+	uploadBytes := testutil.ToFloat64(monitoring.UploadBytes.WithLabelValues("Brand"))
+	uploadFailures := testutil.ToFloat64(monitoring.UploadFailures.WithLabelValues("Topic"))
+
+	inquirer.On("Inquire", []string{"Brand", "Topic"}, concept.Options{}, "tid_1234").
+		Return([]*concept.Worker{newTestWorker("Brand", db.Concept{Id: "brand1"}, db.Concept{Id: "brand2"}), newTestWorker("Topic", db.Concept{Id: "topic"})})
+	var brandCsv []byte
+	updater.On("Upload", mock.Anything, "Brand.csv", "tid_1234").Run(func(args mock.Arguments) {
+		brandCsv = args.Get(0).([]byte)
+	}).Return(nil)
+	updater.On("Upload", mock.Anything, "Topic.csv", "tid_1234").Return(errors.New("S3 err"))
+	updater.On("Upload", mock.Anything, mock.Anything, "tid_1234").Return(nil)
+
+	runTestJob(t, exporter, []string{"Brand", "Topic"}, concept.Options{})
+
+	assert.Equal(t, rowsRead+2, testutil.ToFloat64(monitoring.RowsRead.WithLabelValues("Brand")))
+	assert.Equal(t, rowsWritten+2, testutil.ToFloat64(monitoring.RowsWritten.WithLabelValues("Brand")))
+	assert.Equal(t, uploadBytes+float64(len(brandCsv)), testutil.ToFloat64(monitoring.UploadBytes.WithLabelValues("Brand")))
+	assert.Equal(t, uploadFailures+1, testutil.ToFloat64(monitoring.UploadFailures.WithLabelValues("Topic")))
+	assert.NotZero(t, testutil.ToFloat64(monitoring.LastSuccess.WithLabelValues("Brand")))
+}
+
+func TestFullExporter_StartJobLeavesOutDefaultOptions(t *testing.T) {
+	updater := new(mockUpdater)
+	inquirer := new(mockInquirer)
+	exporter := NewFullExporter(1, updater, inquirer, NewCsvExporter(), logger.NewUPPLogger("Test", "PANIC"))
+	inquirer.On("Inquire", []string{"Brand"}, mock.Anything, "tid_1234").Return([]*concept.Worker{})
+	updater.On("Upload", mock.Anything, mock.Anything, "tid_1234").Return(nil)
+
+	job := runTestJob(t, exporter, []string{"Brand"}, concept.Options{})
+	content, err := json.Marshal(&job)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "Options")
+
+	job = runTestJob(t, exporter, []string{"Brand"}, concept.Options{Annotations: true})
+	content, err = json.Marshal(&job)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"Options":{"Annotations":true}`)
+}
@@ -0,0 +1,97 @@
+package lease
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"time"
+)
+
+//FileStore keeps the lease in a JSON file locked while it's read or written, for replicas sharing a file system
+type FileStore struct {
+	Path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) TryAcquire(holder string, ttl time.Duration, jobs []byte) (Lease, error) {
+	var result Lease
+	err := s.update(func(l *Lease) bool {
+		now := time.Now()
+		if !l.free(holder, now) {
+			result = *l
+			return false
+		}
+		*l = Lease{Holder: holder, Expires: now.Add(ttl), Jobs: jobs}
+		result = *l
+		return true
+	})
+	return result, err
+}
+
+func (s *FileStore) Release(holder string) error {
+	return s.update(func(l *Lease) bool {
+		if l.Holder != holder {
+			return false
+		}
+		l.Holder = ""
+		l.Expires = time.Time{}
+		return true
+	})
+}
+
+func (s *FileStore) Get() (Lease, error) {
+	f, err := os.OpenFile(s.Path, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return Lease{}, err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
+		return Lease{}, err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return read(f)
+}
+
+//update changes the lease under an exclusive lock of the file, writing it back when change returns true
+func (s *FileStore) update(change func(l *Lease) bool) error {
+	f, err := os.OpenFile(s.Path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	l, err := read(f)
+	if err != nil {
+		return err
+	}
+	if !change(&l) {
+		return nil
+	}
+	content, err := json.Marshal(&l)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(content, 0)
+	return err
+}
+
+func read(f *os.File) (Lease, error) {
+	var l Lease
+	content, err := ioutil.ReadAll(f)
+	if err != nil || len(content) == 0 {
+		return l, err
+	}
+	err = json.Unmarshal(content, &l)
+	return l, err
+}
@@ -0,0 +1,73 @@
+package lease
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFileStore(t *testing.T) (*FileStore, func()) {
+	dir, err := ioutil.TempDir("", "lease")
+	assert.NoError(t, err)
+	return NewFileStore(filepath.Join(dir, "concept-exporter.lease")), func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestFileStore_TryAcquire(t *testing.T) {
+	store, cleanup := newTestFileStore(t)
+	defer cleanup()
+
+	l, err := store.TryAcquire("pod-1", time.Minute, []byte(`[{"ID":"job_1"}]`))
+	assert.NoError(t, err)
+	assert.True(t, l.Held("pod-1", time.Now()))
+
+	l, err = store.TryAcquire("pod-2", time.Minute, []byte(`[{"ID":"job_2"}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, "pod-1", l.Holder)
+	assert.False(t, l.Held("pod-2", time.Now()))
+
+	l, err = store.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "pod-1", l.Holder)
+	assert.JSONEq(t, `[{"ID":"job_1"}]`, string(l.Jobs))
+}
+
+func TestFileStore_TryAcquireExpired(t *testing.T) {
+	store, cleanup := newTestFileStore(t)
+	defer cleanup()
+
+	_, err := store.TryAcquire("pod-1", time.Millisecond, nil)
+	assert.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+
+	l, err := store.TryAcquire("pod-2", time.Minute, nil)
+	assert.NoError(t, err)
+	assert.True(t, l.Held("pod-2", time.Now()))
+}
+
+func TestFileStore_Release(t *testing.T) {
+	store, cleanup := newTestFileStore(t)
+	defer cleanup()
+
+	_, err := store.TryAcquire("pod-1", time.Minute, []byte(`[{"ID":"job_1"}]`))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Release("pod-2"))
+	l, err := store.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "pod-1", l.Holder)
+
+	assert.NoError(t, store.Release("pod-1"))
+	l, err = store.Get()
+	assert.NoError(t, err)
+	assert.Empty(t, l.Holder)
+	assert.JSONEq(t, `[{"ID":"job_1"}]`, string(l.Jobs))
+
+	l, err = store.TryAcquire("pod-2", time.Minute, nil)
+	assert.NoError(t, err)
+	assert.True(t, l.Held("pod-2", time.Now()))
+}
@@ -0,0 +1,157 @@
+package lease
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger/v2"
+)
+
+//Lease is the exclusive right of a replica to run the export jobs until it expires.
+//The holder keeps the JSON of its jobs in the lease, so any replica can report them
+type Lease struct {
+	Holder  string          `json:"Holder"`
+	Expires time.Time       `json:"Expires"`
+	Jobs    json.RawMessage `json:"Jobs,omitempty"`
+}
+
+//Held tells whether the lease is held by the given holder at the given time
+func (l Lease) Held(holder string, now time.Time) bool {
+	return l.Holder == holder && now.Before(l.Expires)
+}
+
+func (l Lease) free(holder string, now time.Time) bool {
+	return l.Holder == "" || l.Holder == holder || !now.Before(l.Expires)
+}
+
+//Store keeps the lease shared by the replicas
+type Store interface {
+	//TryAcquire takes or renews the lease for the holder when it's free, expired or already held by it, and returns the resulting lease
+	TryAcquire(holder string, ttl time.Duration, jobs []byte) (Lease, error)
+	//Release frees the lease if it's held by the holder, keeping its last jobs
+	Release(holder string) error
+	Get() (Lease, error)
+}
+
+//Elector keeps renewing the lease of a replica, which is the leader for as long as it holds it
+type Elector struct {
+	sync.RWMutex
+	Store         Store
+	Holder        string
+	TTL           time.Duration
+	RenewInterval time.Duration
+	Jobs          func() ([]byte, error)
+	Log           *logger.UPPLogger
+	expires       time.Time
+	leader        Lease
+	fetched       time.Time
+	stop          chan struct{}
+	done          chan struct{}
+}
+
+func NewElector(store Store, holder string, ttl time.Duration, jobs func() ([]byte, error), log *logger.UPPLogger) *Elector {
+	return &Elector{
+		Store:         store,
+		Holder:        holder,
+		TTL:           ttl,
+		RenewInterval: ttl / 3,
+		Jobs:          jobs,
+		Log:           log,
+	}
+}
+
+//Start tries to take the lease right away, then keeps renewing it in the background
+func (e *Elector) Start() {
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+	e.renew()
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.RenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				e.renew()
+			}
+		}
+	}()
+}
+
+//Stop stops renewing the lease and releases it, so another replica can take over without waiting for its expiry
+func (e *Elector) Stop() {
+	close(e.stop)
+	<-e.done
+	e.Lock()
+	e.expires = time.Time{}
+	e.fetched = time.Time{}
+	e.Unlock()
+	if err := e.Store.Release(e.Holder); err != nil {
+		e.Log.WithError(err).Warnf("Failed to release the lease of %v", e.Holder)
+	}
+}
+
+//IsLeader tells whether this replica holds the lease. A replica failing to renew its lease stops being the leader once it expired
+func (e *Elector) IsLeader() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return time.Now().Before(e.expires)
+}
+
+//Leader returns the lease of the leader with its jobs. The lease only changes when it's renewed,
+//so the one read at the last renewal is returned until it's older than the renewal interval
+func (e *Elector) Leader() (Lease, error) {
+	e.RLock()
+	l, fetched := e.leader, e.fetched
+	e.RUnlock()
+	if time.Since(fetched) < e.RenewInterval {
+		return l, nil
+	}
+	l, err := e.Store.Get()
+	if err != nil {
+		return Lease{}, err
+	}
+	e.keep(l, time.Now())
+	return l, nil
+}
+
+func (e *Elector) keep(l Lease, fetched time.Time) {
+	e.Lock()
+	defer e.Unlock()
+	if fetched.After(e.fetched) {
+		e.leader, e.fetched = l, fetched
+	}
+}
+
+func (e *Elector) renew() {
+	jobs, err := e.Jobs()
+	if err != nil {
+		e.Log.WithError(err).Warn("Failed to marshal the jobs for the lease")
+		jobs = nil
+	}
+	now := time.Now()
+	l, err := e.Store.TryAcquire(e.Holder, e.TTL, jobs)
+	if err != nil {
+		e.Log.WithError(err).Warnf("Failed to renew the lease of %v", e.Holder)
+		return
+	}
+	e.keep(l, now)
+	wasLeader := e.IsLeader()
+	e.Lock()
+	if l.Held(e.Holder, now) {
+		e.expires = now.Add(e.TTL)
+	} else {
+		e.expires = time.Time{}
+	}
+	e.Unlock()
+	isLeader := e.IsLeader()
+	switch {
+	case isLeader && !wasLeader:
+		e.Log.Infof("%v took the lease and runs the export jobs", e.Holder)
+	case !isLeader && wasLeader:
+		e.Log.Warnf("%v lost the lease to %v", e.Holder, l.Holder)
+	}
+}
@@ -0,0 +1,78 @@
+package lease
+
+import (
+	"testing"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElector_SingleLeader(t *testing.T) {
+	store, cleanup := newTestFileStore(t)
+	defer cleanup()
+	log := logger.NewUPPLogger("Test", "PANIC")
+	jobs := func(id string) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			return []byte(`[{"ID":"` + id + `"}]`), nil
+		}
+	}
+	first := NewElector(store, "pod-1", time.Second, jobs("job_1"), log)
+	second := NewElector(store, "pod-2", time.Second, jobs("job_2"), log)
+	second.RenewInterval = 10 * time.Millisecond
+
+	first.Start()
+	second.Start()
+	assert.True(t, first.IsLeader())
+	assert.False(t, second.IsLeader())
+
+	l, err := second.Leader()
+	assert.NoError(t, err)
+	assert.Equal(t, "pod-1", l.Holder)
+	assert.JSONEq(t, `[{"ID":"job_1"}]`, string(l.Jobs))
+
+	first.Stop()
+	assert.False(t, first.IsLeader())
+	assert.Eventually(t, second.IsLeader, time.Second, 10*time.Millisecond)
+	second.Stop()
+}
+
+type countingStore struct {
+	Store
+	gets int
+}
+
+func (s *countingStore) Get() (Lease, error) {
+	s.gets++
+	return s.Store.Get()
+}
+
+func TestElector_LeaderIsReadOncePerRenewal(t *testing.T) {
+	fileStore, cleanup := newTestFileStore(t)
+	defer cleanup()
+	log := logger.NewUPPLogger("Test", "PANIC")
+	_, err := fileStore.TryAcquire("pod-1", time.Minute, []byte(`[{"ID":"job_1"}]`))
+	assert.NoError(t, err)
+	noJobs := func() ([]byte, error) { return nil, nil }
+
+	store := &countingStore{Store: fileStore}
+	follower := NewElector(store, "pod-2", time.Minute, noJobs, log)
+	follower.Start()
+	defer follower.Stop()
+	for i := 0; i < 10; i++ {
+		l, err := follower.Leader()
+		assert.NoError(t, err)
+		assert.Equal(t, "pod-1", l.Holder)
+		assert.JSONEq(t, `[{"ID":"job_1"}]`, string(l.Jobs))
+	}
+	assert.Equal(t, 0, store.gets, "the lease read at the renewal should be returned")
+
+	store = &countingStore{Store: fileStore}
+	notStarted := NewElector(store, "pod-3", time.Minute, noJobs, log)
+	for i := 0; i < 10; i++ {
+		l, err := notStarted.Leader()
+		assert.NoError(t, err)
+		assert.Equal(t, "pod-1", l.Holder)
+	}
+	assert.Equal(t, 1, store.gets, "the lease should be read from the store once per renewal interval")
+}
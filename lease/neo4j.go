@@ -0,0 +1,115 @@
+package lease
+
+import (
+	"time"
+
+	"github.com/Financial-Times/neo-utils-go/v2/neoutils"
+	"github.com/jmcvetta/neoism"
+)
+
+const neoLeaseLabel = "ConceptExporterLease"
+
+//NeoStore keeps the lease in a node of the Neo4j cluster the concepts are exported from
+type NeoStore struct {
+	Connection neoutils.NeoConnection
+	Name       string
+}
+
+func NewNeoStore(conn neoutils.NeoConnection, name string) *NeoStore {
+	return &NeoStore{Connection: conn, Name: name}
+}
+
+//Initialise makes sure there's a single lease node with the name of the store
+func (s *NeoStore) Initialise() error {
+	return s.Connection.EnsureConstraints(map[string]string{neoLeaseLabel: "name"})
+}
+
+type neoLease struct {
+	Holder  string
+	Expires int64
+	Jobs    string
+}
+
+func (l neoLease) lease() Lease {
+	result := Lease{Holder: l.Holder}
+	if l.Expires != 0 {
+		result.Expires = time.Unix(0, l.Expires*int64(time.Millisecond))
+	}
+	if l.Jobs != "" {
+		result.Jobs = []byte(l.Jobs)
+	}
+	return result
+}
+
+//TryAcquire sets and removes a property of the lease node before checking it, so the write lock held until the end of the transaction
+//keeps the other replicas from taking the lease at the same time
+func (s *NeoStore) TryAcquire(holder string, ttl time.Duration, jobs []byte) (Lease, error) {
+	now := time.Now()
+	var results []neoLease
+	query := &neoism.CypherQuery{
+		Statement: `
+		MERGE (l:ConceptExporterLease {name: {name}})
+		SET l.locked = true
+		REMOVE l.locked
+		WITH l, (coalesce(l.holder, '') IN ['', {holder}] OR coalesce(l.expires, 0) <= {now}) AS free
+		FOREACH (ignored IN CASE WHEN free THEN [1] ELSE [] END |
+			SET l.holder = {holder}, l.expires = {expires}, l.jobs = {jobs})
+		RETURN l.holder AS Holder, l.expires AS Expires, l.jobs AS Jobs
+		`,
+		Parameters: neoism.Props{
+			"name":    s.Name,
+			"holder":  holder,
+			"now":     millis(now),
+			"expires": millis(now.Add(ttl)),
+			"jobs":    string(jobs),
+		},
+		Result: &results,
+	}
+	if err := s.Connection.CypherBatch([]*neoism.CypherQuery{query}); err != nil {
+		return Lease{}, err
+	}
+	if len(results) == 0 {
+		return Lease{}, nil
+	}
+	return results[0].lease(), nil
+}
+
+func (s *NeoStore) Release(holder string) error {
+	query := &neoism.CypherQuery{
+		Statement: `
+		MATCH (l:ConceptExporterLease {name: {name}})
+		WHERE l.holder = {holder}
+		SET l.holder = '', l.expires = 0
+		`,
+		Parameters: neoism.Props{
+			"name":   s.Name,
+			"holder": holder,
+		},
+	}
+	return s.Connection.CypherBatch([]*neoism.CypherQuery{query})
+}
+
+func (s *NeoStore) Get() (Lease, error) {
+	var results []neoLease
+	query := &neoism.CypherQuery{
+		Statement: `
+		MATCH (l:ConceptExporterLease {name: {name}})
+		RETURN l.holder AS Holder, l.expires AS Expires, l.jobs AS Jobs
+		`,
+		Parameters: neoism.Props{
+			"name": s.Name,
+		},
+		Result: &results,
+	}
+	if err := s.Connection.CypherBatch([]*neoism.CypherQuery{query}); err != nil {
+		return Lease{}, err
+	}
+	if len(results) == 0 {
+		return Lease{}, nil
+	}
+	return results[0].lease(), nil
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
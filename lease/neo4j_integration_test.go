@@ -0,0 +1,70 @@
+// +build integration
+
+package lease
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger/v2"
+	"github.com/Financial-Times/neo-utils-go/v2/neoutils"
+	"github.com/jmcvetta/neoism"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNeoStore(t *testing.T) (*NeoStore, func()) {
+	if testing.Short() {
+		t.Skip("Neo4j integration for long tests only.")
+	}
+	url := os.Getenv("NEO4J_TEST_URL")
+	if url == "" {
+		url = "http://localhost:7474/db/data"
+	}
+
+	conf := neoutils.DefaultConnectionConfig()
+	conf.Transactional = false
+	conn, err := neoutils.Connect(url, conf, logger.NewUPPLogger("test-concept-exporter", "PANIC"))
+	assert.NoError(t, err, "Failed to connect to Neo4j")
+
+	store := NewNeoStore(conn, "test-concept-exporter")
+	assert.NoError(t, store.Initialise())
+	return store, func() {
+		query := &neoism.CypherQuery{
+			Statement:  `MATCH (l:ConceptExporterLease {name: {name}}) DELETE l`,
+			Parameters: neoism.Props{"name": store.Name},
+		}
+		assert.NoError(t, conn.CypherBatch([]*neoism.CypherQuery{query}))
+	}
+}
+
+func TestNeoStore_TryAcquire(t *testing.T) {
+	store, cleanup := newTestNeoStore(t)
+	defer cleanup()
+
+	l, err := store.TryAcquire("pod-1", time.Minute, []byte(`[{"ID":"job_1"}]`))
+	assert.NoError(t, err)
+	assert.True(t, l.Held("pod-1", time.Now()))
+
+	l, err = store.TryAcquire("pod-2", time.Minute, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "pod-1", l.Holder)
+
+	l, err = store.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "pod-1", l.Holder)
+	assert.JSONEq(t, `[{"ID":"job_1"}]`, string(l.Jobs))
+}
+
+func TestNeoStore_Release(t *testing.T) {
+	store, cleanup := newTestNeoStore(t)
+	defer cleanup()
+
+	_, err := store.TryAcquire("pod-1", time.Minute, []byte(`[{"ID":"job_1"}]`))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Release("pod-1"))
+
+	l, err := store.TryAcquire("pod-2", time.Minute, nil)
+	assert.NoError(t, err)
+	assert.True(t, l.Held("pod-2", time.Now()))
+}
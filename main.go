@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/Financial-Times/concept-exporter/auth"
 	"github.com/Financial-Times/concept-exporter/concept"
 	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/concept-exporter/event"
 	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/lease"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/Financial-Times/concept-exporter/schedule"
+	"github.com/Financial-Times/concept-exporter/tracing"
 	"github.com/Financial-Times/concept-exporter/web"
 	health "github.com/Financial-Times/go-fthealth/v1_1"
 	"github.com/Financial-Times/go-logger/v2"
@@ -20,12 +29,16 @@ import (
 	status "github.com/Financial-Times/service-status-go/httphandlers"
 	"github.com/gorilla/mux"
 	cli "github.com/jawher/mow.cli"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rcrowley/go-metrics"
 	"github.com/sethgrid/pester"
 )
 
 const appDescription = "Exports concept from a data source (Neo4j) and sends it to S3"
 
+//schedulerStopTimeout bounds the wait for the scheduled runs handing their jobs to the exporter on shutdown
+const schedulerStopTimeout = 30 * time.Second
+
 func main() {
 	app := cli.App("concept-exporter", appDescription)
 
@@ -65,12 +78,120 @@ func main() {
 		Desc:   "Health URL to S3 writer endpoint",
 		EnvVar: "S3_WRITER_HEALTH_URL",
 	})
+	sinkProbe := app.Bool(cli.BoolOpt{
+		Name:   "sinkProbe",
+		Value:  false,
+		Desc:   "Check the bucket in dry runs by writing and deleting a probe file under preflight/ instead of reading the health of the S3 writer",
+		EnvVar: "SINK_PROBE",
+	})
 	conceptTypes := app.Strings(cli.StringsOpt{
 		Name:   "conceptTypes",
 		Value:  []string{"Brand", "Topic", "Location", "Person", "Organisation"},
 		Desc:   "Concept types to support",
 		EnvVar: "CONCEPT_TYPES",
 	})
+	atomicPublish := app.Bool(cli.BoolOpt{
+		Name:   "atomicPublish",
+		Value:  false,
+		Desc:   "Upload the files of a job under its own keys and publish them only once every concept type succeeded, by uploading the latest manifest naming these keys",
+		EnvVar: "ATOMIC_PUBLISH",
+	})
+	keyTemplate := app.String(cli.StringOpt{
+		Name:   "keyTemplate",
+		Value:  "",
+		Desc:   "Template of the keys the exported files of every job are archived to, e.g. {type}/{yyyy}/{mm}/{dd}/{jobId}.{ext}. Empty disables archiving",
+		EnvVar: "KEY_TEMPLATE",
+	})
+	schedules := app.String(cli.StringOpt{
+		Name:   "schedules",
+		Value:  "",
+		Desc:   `JSON array of scheduled exports, e.g. [{"Name":"nightly","Cron":"0 2 * * *","ConceptTypes":["Brand"],"Options":{"Annotations":true},"Queue":true}]. Empty disables scheduling`,
+		EnvVar: "SCHEDULES",
+	})
+	callbackURLs := app.Strings(cli.StringsOpt{
+		Name:   "callbackUrls",
+		Value:  []string{},
+		Desc:   "URLs notified of every finished job",
+		EnvVar: "CALLBACK_URLS",
+	})
+	callbackHosts := app.Strings(cli.StringsOpt{
+		Name:   "callbackHosts",
+		Value:  []string{},
+		Desc:   "Hosts the callback URLs of export requests may point to, next to the hosts of the callbackUrls. Empty rejects the callback URLs of requests on other hosts",
+		EnvVar: "CALLBACK_HOSTS",
+	})
+	callbackSecret := app.String(cli.StringOpt{
+		Name:   "callbackSecret",
+		Value:  "",
+		Desc:   "Secret the notifications of finished jobs are signed with. Empty disables signing",
+		EnvVar: "CALLBACK_SECRET",
+	})
+	kafkaBrokers := app.Strings(cli.StringsOpt{
+		Name:   "kafkaBrokers",
+		Value:  []string{},
+		Desc:   "Addresses of the Kafka brokers the job lifecycle events are published to. Empty disables publishing",
+		EnvVar: "KAFKA_BROKERS",
+	})
+	kafkaTopic := app.String(cli.StringOpt{
+		Name:   "kafkaTopic",
+		Value:  "ConceptExportEvents",
+		Desc:   "Kafka topic the job lifecycle events are published to",
+		EnvVar: "KAFKA_TOPIC",
+	})
+	retries := app.Int(cli.IntOpt{
+		Name:   "retries",
+		Value:  0,
+		Desc:   "How many times the failed types of a job are exported again before the job finishes",
+		EnvVar: "RETRIES",
+	})
+	queueSize := app.Int(cli.IntOpt{
+		Name:   "queueSize",
+		Value:  10,
+		Desc:   "How many export requests are queued while a job is running before new ones are rejected",
+		EnvVar: "QUEUE_SIZE",
+	})
+	streamLimit := app.Int(cli.IntOpt{
+		Name:   "streamLimit",
+		Value:  2,
+		Desc:   "How many exports are streamed or previewed over HTTP at the same time before new ones are rejected. Zero disables the streamed exports and the previews",
+		EnvVar: "STREAM_LIMIT",
+	})
+	leaseStore := app.String(cli.StringOpt{
+		Name:   "leaseStore",
+		Value:  "",
+		Desc:   "Where the lease electing the single replica running the export jobs is kept: file or neo4j. Empty runs the jobs on every replica",
+		EnvVar: "LEASE_STORE",
+	})
+	leaseFile := app.String(cli.StringOpt{
+		Name:   "leaseFile",
+		Value:  "/tmp/concept-exporter.lease",
+		Desc:   "Path of the lease file shared by the replicas, with the file lease store",
+		EnvVar: "LEASE_FILE",
+	})
+	leaseTTL := app.Int(cli.IntOpt{
+		Name:   "leaseTtl",
+		Value:  30,
+		Desc:   "Seconds the lease is held for without being renewed",
+		EnvVar: "LEASE_TTL",
+	})
+	apiKeys := app.String(cli.StringOpt{
+		Name:   "apiKeys",
+		Value:  "",
+		Desc:   `JSON array of the API keys allowed to call the service, e.g. [{"ID":"ops","Secret":"...","Scopes":["read","export:*","retry","rollback"]}]. Empty disables authentication`,
+		EnvVar: "API_KEYS",
+	})
+	otlpEndpoint := app.String(cli.StringOpt{
+		Name:   "otlpEndpoint",
+		Value:  "",
+		Desc:   "host:port of the OTLP/HTTP collector the traces are exported to. Empty disables exporting traces",
+		EnvVar: "OTLP_ENDPOINT",
+	})
+	otlpInsecure := app.Bool(cli.BoolOpt{
+		Name:   "otlpInsecure",
+		Value:  false,
+		Desc:   "Export the traces over HTTP instead of HTTPS",
+		EnvVar: "OTLP_INSECURE",
+	})
 	logLevel := app.String(cli.StringOpt{
 		Name:   "log-level",
 		Value:  "info",
@@ -82,6 +203,15 @@ func main() {
 
 	app.Action = func() {
 		log.WithField("service_name", *appName).Info("Service started")
+		shutdownTracing, err := tracing.Init(context.Background(), *otlpEndpoint, *otlpInsecure, *appSystemCode)
+		if err != nil {
+			log.Fatalf("Can't set up tracing, error=[%s]\n", err)
+		}
+		defer func() {
+			if err := shutdownTracing(context.Background()); err != nil {
+				log.Errorf("unable to flush traces: %v", err)
+			}
+		}()
 		conf := neoutils.DefaultConnectionConfig()
 		conf.HTTPClient.Timeout = 10 * time.Minute
 		neoConn, err := neoutils.Connect(*neoURL, conf, log)
@@ -107,8 +237,60 @@ func main() {
 
 		uploader := &concept.S3Updater{Client: client, S3WriterBaseURL: *s3WriterBaseURL, S3WriterHealthURL: *s3WriterHealthURL}
 		neoService := db.NewNeoService(neoConn, *neoURL)
-		fullExporter := export.NewFullExporter(30, uploader, concept.NewNeoInquirer(neoService, log),
-			export.NewCsvExporter(), log)
+		inquirer := concept.NewNeoInquirer(neoService, log)
+		fullExporter := export.NewFullExporter(30, uploader, inquirer, export.NewCsvExporter(), log)
+		fullExporter.Counter = inquirer
+		fullExporter.Preflights = []export.Preflight{
+			{Name: "S3 Writer", Check: func(string) (string, error) { return uploader.CheckHealth(client) }},
+			{Name: "S3 bucket", Check: func(tid string) (string, error) { return uploader.CheckSink(client, tid) }},
+		}
+		if *sinkProbe {
+			fullExporter.Preflights[1].Check = func(tid string) (string, error) { return uploader.ProbeSink(client, tid) }
+		}
+		fullExporter.AtomicPublish = *atomicPublish
+		fullExporter.KeyTemplate = export.KeyTemplate(*keyTemplate)
+		if fullExporter.KeyTemplate != "" {
+			if err := fullExporter.KeyTemplate.Validate(); err != nil {
+				log.Fatalf("Can't use the key template, error=[%s]\n", err)
+			}
+		}
+		fullExporter.Fetcher = uploader
+		fullExporter.Retries = *retries
+		fullExporter.QueueSize = *queueSize
+		fullExporter.Notifier = &export.Notifier{Client: client, URLs: *callbackURLs, Secret: *callbackSecret, Log: log}
+		if len(*kafkaBrokers) != 0 {
+			publisher := event.NewKafkaPublisher(*kafkaBrokers, *kafkaTopic, log)
+			defer publisher.Close()
+			fullExporter.Events = publisher
+		}
+
+		elector, err := newElector(*leaseStore, *leaseFile, time.Duration(*leaseTTL)*time.Second, neoConn, fullExporter, log)
+		if err != nil {
+			log.Fatalf("Can't set up the lease, error=[%s]\n", err)
+		}
+		if elector != nil {
+			fullExporter.Leader = elector
+			elector.Start()
+			defer elector.Stop()
+		}
+
+		profiles, err := schedule.ParseProfiles(*schedules)
+		if err != nil {
+			log.Fatalf("Can't read schedules, error=[%s]\n", err)
+		}
+		scheduler, err := schedule.NewScheduler(profiles, *conceptTypes, fullExporter, log)
+		if err != nil {
+			log.Fatalf("Can't create scheduler, error=[%s]\n", err)
+		}
+		if elector != nil {
+			scheduler.Leader = elector
+		}
+		scheduler.Start()
+		defer func() {
+			if !scheduler.Stop(schedulerStopTimeout) {
+				log.Warnf("Stopped waiting for the running scheduled exports after %v", schedulerStopTimeout)
+			}
+		}()
 
 		healthService := newHealthService(
 			&healthConfig{
@@ -119,7 +301,23 @@ func main() {
 				neoService:    neoService,
 				log:           log,
 			})
-		serveEndpoints(*appSystemCode, *appName, *port, web.NewRequestHandler(fullExporter, *conceptTypes, log), healthService, log)
+		requestHandler := web.NewRequestHandler(fullExporter, *conceptTypes, log)
+		requestHandler.Scheduler = scheduler
+		requestHandler.Elector = elector
+		requestHandler.CallbackHosts = append(hostsOf(*callbackURLs), *callbackHosts...)
+		if *streamLimit > 0 {
+			requestHandler.Previewer = inquirer
+			requestHandler.Streamer = inquirer
+			requestHandler.Streams = make(chan struct{}, *streamLimit)
+		}
+		keys, err := auth.ParseKeys(*apiKeys)
+		if err != nil {
+			log.Fatalf("Can't read API keys, error=[%s]\n", err)
+		}
+		if len(keys) != 0 {
+			requestHandler.Auth = auth.NewAuthenticator(keys)
+		}
+		serveEndpoints(*appSystemCode, *appName, *port, requestHandler, healthService, log)
 	}
 	err := app.Run(os.Args)
 	if err != nil {
@@ -128,6 +326,44 @@ func main() {
 	}
 }
 
+//newElector elects the replica running the export jobs with the lease kept in the given store, sharing the jobs of the exporter
+func newElector(store, file string, ttl time.Duration, neoConn neoutils.NeoConnection, fullExporter *export.FullExporter, log *logger.UPPLogger) (*lease.Elector, error) {
+	var leaseStore lease.Store
+	switch store {
+	case "":
+		return nil, nil
+	case "file":
+		leaseStore = lease.NewFileStore(file)
+	case "neo4j":
+		neoStore := lease.NewNeoStore(neoConn, "concept-exporter")
+		if err := neoStore.Initialise(); err != nil {
+			return nil, err
+		}
+		leaseStore = neoStore
+	default:
+		return nil, fmt.Errorf("unsupported lease store %v", store)
+	}
+	holder, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return lease.NewElector(leaseStore, holder, ttl, func() ([]byte, error) {
+		jobs := fullExporter.GetJobs()
+		return json.Marshal(&jobs)
+	}, log), nil
+}
+
+//hostsOf returns the hosts of the URLs, so the callback URLs of requests may point to the hosts already notified of every job
+func hostsOf(urls []string) []string {
+	var hosts []string
+	for _, u := range urls {
+		if parsed, err := url.Parse(u); err == nil && parsed.Host != "" {
+			hosts = append(hosts, parsed.Host)
+		}
+	}
+	return hosts
+}
+
 func serveEndpoints(appSystemCode string, appName string, port string, requestHandler *web.RequestHandler,
 	healthService *healthService, log *logger.UPPLogger) {
 
@@ -137,17 +373,42 @@ func serveEndpoints(appSystemCode string, appName string, port string, requestHa
 	serveMux.HandleFunc(healthPath, health.Handler(hc))
 	serveMux.HandleFunc(status.GTGPath, status.NewGoodToGoHandler(healthService.GTG))
 	serveMux.HandleFunc(status.BuildInfoPath, status.BuildInfoHandler)
+	serveMux.Handle("/metrics", promhttp.Handler())
 
 	servicesRouter := mux.NewRouter()
 
 	servicesRouter.HandleFunc("/export", requestHandler.Export).Methods(http.MethodPost)
+	if requestHandler.Streamer != nil {
+		servicesRouter.HandleFunc("/export/{conceptType}", requestHandler.GetExportStream).Methods(http.MethodGet)
+	}
+	if requestHandler.Previewer != nil {
+		servicesRouter.HandleFunc("/preview/{conceptType}", requestHandler.GetPreview).Methods(http.MethodGet)
+	}
 	servicesRouter.HandleFunc("/job", requestHandler.GetJob).Methods(http.MethodGet)
+	servicesRouter.HandleFunc("/jobs", requestHandler.GetJobs).Methods(http.MethodGet)
+	servicesRouter.HandleFunc("/jobs/{id}", requestHandler.GetJobByID).Methods(http.MethodGet)
+	servicesRouter.HandleFunc("/jobs/{id}/events", requestHandler.GetJobEvents).Methods(http.MethodGet)
+	servicesRouter.HandleFunc("/jobs/{id}/rollback", requestHandler.Rollback).Methods(http.MethodPost)
+	servicesRouter.HandleFunc("/jobs/{id}/retry", requestHandler.Retry).Methods(http.MethodPost)
+	servicesRouter.HandleFunc("/schedules", requestHandler.GetSchedules).Methods(http.MethodGet)
+	servicesRouter.HandleFunc("/schema", requestHandler.GetSchema).Methods(http.MethodGet)
+	servicesRouter.HandleFunc("/openapi.json", requestHandler.GetOpenAPI).Methods(http.MethodGet)
+	if requestHandler.Auth != nil {
+		servicesRouter.Use(requestHandler.Auth.Middleware)
+	}
+	servicesRouter.NotFoundHandler = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		problem.Write(writer, http.StatusNotFound, fmt.Sprintf("No endpoint at %v", request.URL.Path), nil)
+	})
+	servicesRouter.MethodNotAllowedHandler = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		problem.Write(writer, http.StatusMethodNotAllowed, fmt.Sprintf("%v is not allowed on %v", request.Method, request.URL.Path), nil)
+	})
 
 	var monitoringRouter http.Handler = servicesRouter
 	monitoringRouter = httphandlers.TransactionAwareRequestLoggingHandler(log, monitoringRouter)
 	monitoringRouter = httphandlers.HTTPMetricsHandler(metrics.DefaultRegistry, monitoringRouter)
 
-	serveMux.Handle("/", monitoringRouter)
+	//kept outside the logging and metrics handlers, whose writers hide the write deadline lifted by the streamed exports
+	serveMux.Handle("/", web.KeepResponseController(monitoringRouter))
 	server := &http.Server{
 		Addr:         ":" + port,
 		Handler:      serveMux,
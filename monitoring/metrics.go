@@ -0,0 +1,74 @@
+package monitoring
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "concept_exporter"
+
+var (
+	QueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "neo4j_query_duration_seconds",
+		Help:      "Duration of the Neo4j queries reading an export type",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"export_type"})
+	RowsRead = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_read_total",
+		Help:      "Rows read from Neo4j",
+	}, []string{"export_type"})
+	RowsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_written_total",
+		Help:      "Rows written to the exported files",
+	}, []string{"export_type"})
+	RowsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_failed_total",
+		Help:      "Rows read from Neo4j which failed to be written to the exported files",
+	}, []string{"export_type"})
+	UploadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_duration_seconds",
+		Help:      "Duration of the uploads to the S3 writer",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"export_type"})
+	UploadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes uploaded to the S3 writer",
+	}, []string{"export_type"})
+	UploadFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_failures_total",
+		Help:      "Failed uploads to the S3 writer",
+	}, []string{"export_type"})
+	JobDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of the export jobs",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+	})
+	LastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time an export type was last published",
+	}, []string{"export_type"})
+	EventsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_dropped_total",
+		Help:      "Job events which weren't published to Kafka, as the buffer was full or the write failed",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(QueryDuration, RowsRead, RowsWritten, RowsFailed, UploadDuration, UploadBytes, UploadFailures, JobDuration, LastSuccess, EventsDropped)
+}
+
+//ObserveSince records the seconds elapsed since start in the histogram
+func ObserveSince(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
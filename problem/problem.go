@@ -0,0 +1,38 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+//ContentType is the media type of the problem details
+const ContentType = "application/problem+json"
+
+//InvalidParam is a field of a request failing the validation, named by its path in the request body
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+//Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+//Write answers the request with the problem details of the status
+func Write(writer http.ResponseWriter, status int, detail string, invalid []InvalidParam) {
+	writer.Header().Set("Content-Type", ContentType)
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.WriteHeader(status)
+	_ = json.NewEncoder(writer).Encode(&Problem{
+		Type:          "about:blank",
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        detail,
+		InvalidParams: invalid,
+	})
+}
@@ -0,0 +1,198 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/export"
+	logger "github.com/Financial-Times/go-logger/v2"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/robfig/cron/v3"
+)
+
+//Exporter runs the jobs of the schedules
+type Exporter interface {
+	QueueJob(ctx context.Context, candidates []string, opts concept.Options, callbacks []string, errMsg, tid string) (export.Job, error)
+	StartJob(ctx context.Context, candidates []string, opts concept.Options, errMsg, tid string) (export.Job, error)
+	GetJob(id string) (export.Job, bool)
+}
+
+//Profile is an export run on a standard cron expression, evaluated in UTC. Empty concept types export every supported type.
+//A run finding a job already running is skipped, unless Queue is set, in which case its job is queued behind the running one
+type Profile struct {
+	Name         string          `json:"Name"`
+	Cron         string          `json:"Cron"`
+	ConceptTypes []string        `json:"ConceptTypes,omitempty"`
+	Options      concept.Options `json:"Options"`
+	Queue        bool            `json:"Queue,omitempty"`
+}
+
+//ParseProfiles reads the profiles from a JSON array
+func ParseProfiles(profiles string) ([]Profile, error) {
+	var result []Profile
+	if profiles == "" {
+		return result, nil
+	}
+	if err := json.Unmarshal([]byte(profiles), &result); err != nil {
+		return nil, fmt.Errorf("invalid schedule profiles: %v", err)
+	}
+	return result, nil
+}
+
+//Schedule is a profile with the times of its runs
+type Schedule struct {
+	Profile
+	NextRun     *time.Time `json:"NextRun,omitempty"`
+	LastRun     *time.Time `json:"LastRun,omitempty"`
+	LastJobID   string     `json:"LastJobID,omitempty"`
+	LastSkipped *time.Time `json:"LastSkipped,omitempty"`
+	Queued      bool       `json:"Queued,omitempty"`
+	spec        cron.Schedule
+}
+
+//Leader tells whether this replica runs the export jobs
+type Leader interface {
+	IsLeader() bool
+}
+
+type Scheduler struct {
+	sync.RWMutex
+	cron      *cron.Cron
+	schedules []*Schedule
+	Exporter  Exporter
+	Leader    Leader
+	Log       *logger.UPPLogger
+}
+
+func NewScheduler(profiles []Profile, conceptTypes []string, exporter Exporter, log *logger.UPPLogger) (*Scheduler, error) {
+	s := &Scheduler{
+		cron:     cron.New(cron.WithLocation(time.UTC)),
+		Exporter: exporter,
+		Log:      log,
+	}
+	for _, p := range profiles {
+		spec, err := cron.ParseStandard(p.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression of schedule %v: %v", p.Name, err)
+		}
+		if len(p.ConceptTypes) == 0 {
+			p.ConceptTypes = conceptTypes
+		}
+		if invalid := p.Options.Validate(); len(invalid) != 0 {
+			return nil, fmt.Errorf("invalid options of schedule %v: %v", p.Name, invalid)
+		}
+		for _, cType := range p.ConceptTypes {
+			if !contains(conceptTypes, cType) {
+				return nil, fmt.Errorf("unsupported concept type %v in schedule %v", cType, p.Name)
+			}
+		}
+		schedule := &Schedule{Profile: p, spec: spec}
+		s.schedules = append(s.schedules, schedule)
+		s.cron.Schedule(spec, cron.FuncJob(func() {
+			s.run(schedule)
+		}))
+	}
+	return s, nil
+}
+
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+//Stop stops triggering runs and waits for the running ones to hand their jobs to the exporter, at most for the timeout. It tells whether they finished
+func (s *Scheduler) Stop(timeout time.Duration) bool {
+	select {
+	case <-s.cron.Stop().Done():
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+//Schedules returns the schedules with the time of their next run and whether the job of their last run is still queued
+func (s *Scheduler) Schedules() []Schedule {
+	s.RLock()
+	defer s.RUnlock()
+	now := time.Now().UTC()
+	result := []Schedule{}
+	for _, schedule := range s.schedules {
+		next := schedule.spec.Next(now)
+		result = append(result, Schedule{
+			Profile:     schedule.Profile,
+			NextRun:     &next,
+			LastRun:     schedule.LastRun,
+			LastJobID:   schedule.LastJobID,
+			LastSkipped: schedule.LastSkipped,
+			Queued:      s.isQueued(schedule),
+		})
+	}
+	return result
+}
+
+func (s *Scheduler) run(schedule *Schedule) {
+	tid := transactionidutils.NewTransactionID()
+	logEntry := s.Log.WithTransactionID(tid)
+	if s.Leader != nil && !s.Leader.IsLeader() {
+		logEntry.Infof("Skipping scheduled export %v as the export jobs are run by the leader replica", schedule.Name)
+		return
+	}
+	s.RLock()
+	queued := s.isQueued(schedule)
+	s.RUnlock()
+	if queued {
+		s.setLastSkipped(schedule)
+		logEntry.Warnf("Skipping scheduled export %v as its previous job is still queued", schedule.Name)
+		return
+	}
+	var job export.Job
+	var err error
+	if schedule.Queue {
+		job, err = s.Exporter.QueueJob(context.Background(), schedule.ConceptTypes, schedule.Options, nil, "", tid)
+	} else {
+		job, err = s.Exporter.StartJob(context.Background(), schedule.ConceptTypes, schedule.Options, "", tid)
+	}
+	if err != nil {
+		s.setLastSkipped(schedule)
+		logEntry.Warnf("Skipping scheduled export %v: %v", schedule.Name, err)
+		return
+	}
+	s.setLastRun(schedule, job.ID)
+	logEntry.Infof("Running scheduled export %v as job %v", schedule.Name, job.ID)
+}
+
+//isQueued tells whether the job of the last run of the schedule is still waiting in the queue of the exporter
+func (s *Scheduler) isQueued(schedule *Schedule) bool {
+	if schedule.LastJobID == "" {
+		return false
+	}
+	job, found := s.Exporter.GetJob(schedule.LastJobID)
+	return found && job.Status == concept.QUEUED
+}
+
+func (s *Scheduler) setLastSkipped(schedule *Schedule) {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now().UTC()
+	schedule.LastSkipped = &now
+}
+
+func (s *Scheduler) setLastRun(schedule *Schedule, jobID string) {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now().UTC()
+	schedule.LastRun = &now
+	schedule.LastJobID = jobID
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
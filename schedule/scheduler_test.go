@@ -0,0 +1,196 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockExporter struct {
+	mock.Mock
+	sync.Mutex
+	status concept.State
+}
+
+func (m *mockExporter) QueueJob(_ context.Context, candidates []string, opts concept.Options, callbacks []string, errMsg, tid string) (export.Job, error) {
+	args := m.Called(candidates, opts, callbacks, errMsg)
+	return export.Job{ID: args.String(0), Status: concept.QUEUED}, args.Error(1)
+}
+
+func (m *mockExporter) StartJob(_ context.Context, candidates []string, opts concept.Options, errMsg, tid string) (export.Job, error) {
+	args := m.Called(candidates, opts, errMsg)
+	return export.Job{ID: args.String(0), Status: concept.STARTING}, args.Error(1)
+}
+
+//GetJob returns every job with the status set by setStatus
+func (m *mockExporter) GetJob(id string) (export.Job, bool) {
+	m.Lock()
+	defer m.Unlock()
+	return export.Job{ID: id, Status: m.status}, m.status != ""
+}
+
+func (m *mockExporter) setStatus(status concept.State) {
+	m.Lock()
+	defer m.Unlock()
+	m.status = status
+}
+
+func TestParseProfiles(t *testing.T) {
+	profiles, err := ParseProfiles(`[{"Name":"nightly","Cron":"0 2 * * *","ConceptTypes":["Brand"],"Options":{"Annotations":true},"Queue":true}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Profile{{Name: "nightly", Cron: "0 2 * * *", ConceptTypes: []string{"Brand"}, Options: concept.Options{Annotations: true}, Queue: true}}, profiles)
+
+	profiles, err = ParseProfiles("")
+	assert.NoError(t, err)
+	assert.Empty(t, profiles)
+
+	_, err = ParseProfiles("nightly")
+	assert.Error(t, err)
+}
+
+func TestNewScheduler(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	tests := []struct {
+		name    string
+		profile Profile
+		err     string
+	}{
+		{
+			name:    "Invalid cron expression",
+			profile: Profile{Name: "nightly", Cron: "at night"},
+			err:     "invalid cron expression of schedule nightly: expected exactly 5 fields, found 2: [at night]",
+		},
+		{
+			name:    "Unsupported concept type",
+			profile: Profile{Name: "nightly", Cron: "0 2 * * *", ConceptTypes: []string{"Genre"}},
+			err:     "unsupported concept type Genre in schedule nightly",
+		},
+		{
+			name:    "Invalid options",
+			profile: Profile{Name: "nightly", Cron: "0 2 * * *", Options: concept.Options{Kind: concept.COOCCURRENCES, Annotations: true, MinCount: -1}},
+			err:     "invalid options of schedule nightly: [options.annotations only applies to the Concepts kind filters.minCount must be an integer of at least 0]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewScheduler([]Profile{test.profile}, []string{"Brand", "Topic"}, new(mockExporter), log)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestScheduler_Schedules(t *testing.T) {
+	scheduler, err := NewScheduler([]Profile{{Name: "nightly", Cron: "0 2 * * *"}}, []string{"Brand", "Topic"}, new(mockExporter), logger.NewUPPLogger("Test", "PANIC"))
+	assert.NoError(t, err)
+
+	schedules := scheduler.Schedules()
+	assert.Len(t, schedules, 1)
+	assert.Equal(t, []string{"Brand", "Topic"}, schedules[0].ConceptTypes)
+	assert.Equal(t, 2, schedules[0].NextRun.Hour())
+	assert.Equal(t, 0, schedules[0].NextRun.Minute())
+	assert.True(t, schedules[0].NextRun.After(time.Now()))
+	assert.Nil(t, schedules[0].LastRun)
+}
+
+func TestNewScheduler_DefaultsTrendingDays(t *testing.T) {
+	scheduler, err := NewScheduler([]Profile{{Name: "hourly", Cron: "0 * * * *", Options: concept.Options{Kind: concept.TRENDING}}}, []string{"Brand"}, new(mockExporter), logger.NewUPPLogger("Test", "PANIC"))
+	assert.NoError(t, err)
+	assert.Equal(t, concept.DefaultTrendingDays, scheduler.Schedules()[0].Options.Days)
+}
+
+func TestScheduler_StopIsBounded(t *testing.T) {
+	exporter := new(mockExporter)
+	scheduler, err := NewScheduler([]Profile{{Name: "minutely", Cron: "* * * * *", ConceptTypes: []string{"Brand"}}}, []string{"Brand"}, exporter, logger.NewUPPLogger("Test", "PANIC"))
+	assert.NoError(t, err)
+	release := make(chan struct{})
+	exporter.On("StartJob", []string{"Brand"}, concept.Options{}, "").Run(func(mock.Arguments) { <-release }).Return("job_1", nil)
+	started := make(chan struct{})
+	var once sync.Once
+	scheduler.cron.Schedule(cron.Every(time.Millisecond), cron.FuncJob(func() {
+		once.Do(func() { close(started) })
+		scheduler.run(scheduler.schedules[0])
+	}))
+	scheduler.Start()
+	<-started
+
+	assert.False(t, scheduler.Stop(10*time.Millisecond))
+	close(release)
+}
+
+func TestScheduler_RunStartsJob(t *testing.T) {
+	exporter := new(mockExporter)
+	scheduler, err := NewScheduler([]Profile{{Name: "nightly", Cron: "0 2 * * *", ConceptTypes: []string{"Brand"}, Options: concept.Options{Concordance: true}}}, []string{"Brand", "Topic"}, exporter, logger.NewUPPLogger("Test", "PANIC"))
+	assert.NoError(t, err)
+	exporter.On("StartJob", []string{"Brand"}, concept.Options{Concordance: true}, "").Return("job_1", nil)
+
+	scheduler.run(scheduler.schedules[0])
+
+	exporter.setStatus(concept.RUNNING)
+	schedule := scheduler.Schedules()[0]
+	assert.Equal(t, "job_1", schedule.LastJobID)
+	assert.NotNil(t, schedule.LastRun)
+	assert.Nil(t, schedule.LastSkipped)
+	assert.False(t, schedule.Queued)
+	exporter.AssertExpectations(t)
+}
+
+func TestScheduler_RunSkipsWhenJobIsRunning(t *testing.T) {
+	exporter := new(mockExporter)
+	scheduler, err := NewScheduler([]Profile{{Name: "nightly", Cron: "0 2 * * *"}}, []string{"Brand"}, exporter, logger.NewUPPLogger("Test", "PANIC"))
+	assert.NoError(t, err)
+	exporter.On("StartJob", []string{"Brand"}, concept.Options{}, "").Return("", export.ErrJobRunning)
+
+	scheduler.run(scheduler.schedules[0])
+
+	schedule := scheduler.Schedules()[0]
+	assert.NotNil(t, schedule.LastSkipped)
+	assert.Nil(t, schedule.LastRun)
+	exporter.AssertExpectations(t)
+}
+
+type follower struct{}
+
+func (follower) IsLeader() bool {
+	return false
+}
+
+func TestScheduler_RunSkipsWhenNotLeader(t *testing.T) {
+	exporter := new(mockExporter)
+	scheduler, err := NewScheduler([]Profile{{Name: "nightly", Cron: "0 2 * * *"}}, []string{"Brand"}, exporter, logger.NewUPPLogger("Test", "PANIC"))
+	assert.NoError(t, err)
+	scheduler.Leader = follower{}
+
+	scheduler.run(scheduler.schedules[0])
+
+	assert.Nil(t, scheduler.Schedules()[0].LastRun)
+	exporter.AssertNotCalled(t, "StartJob", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestScheduler_RunQueuesWhenJobIsRunning(t *testing.T) {
+	exporter := new(mockExporter)
+	scheduler, err := NewScheduler([]Profile{{Name: "nightly", Cron: "0 2 * * *", Queue: true}}, []string{"Brand"}, exporter, logger.NewUPPLogger("Test", "PANIC"))
+	assert.NoError(t, err)
+	exporter.On("QueueJob", []string{"Brand"}, concept.Options{}, []string(nil), "").Return("job_1", nil).Once()
+
+	scheduler.run(scheduler.schedules[0])
+	exporter.setStatus(concept.QUEUED)
+	assert.True(t, scheduler.Schedules()[0].Queued)
+
+	scheduler.run(scheduler.schedules[0])
+	assert.NotNil(t, scheduler.Schedules()[0].LastSkipped)
+
+	exporter.setStatus(concept.RUNNING)
+	schedule := scheduler.Schedules()[0]
+	assert.False(t, schedule.Queued)
+	assert.Equal(t, "job_1", schedule.LastJobID)
+	exporter.AssertExpectations(t)
+}
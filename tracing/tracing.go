@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/Financial-Times/concept-exporter"
+	TransactionIDKey    = attribute.Key("transaction_id")
+)
+
+//Init sets up the W3C trace context propagation and, with an endpoint, the export of the spans to an OTLP/HTTP collector.
+//The returned function flushes the spans not exported yet
+func Init(ctx context.Context, endpoint string, insecure bool, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+//StartFrom starts a span as a child of the span of the context, tagged with the transaction ID
+func StartFrom(parent context.Context, tid, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, TransactionIDKey.String(tid))
+	return otel.Tracer(instrumentationName).Start(parent, name, trace.WithAttributes(attrs...))
+}
+
+//End records the error, if any, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+//Detach returns a context holding the span of the context without its cancellation, e.g. for a job outliving the request creating it
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
+}
+
+//Extract returns the context of the request holding the trace context of its headers
+func Extract(request *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+}
+
+//Inject adds the trace context of the context to the headers of the request
+func Inject(ctx context.Context, request *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	_, err := Init(context.Background(), "", false, "concept-exporter")
+	assert.NoError(t, err)
+	return recorder
+}
+
+func TestStartFromIsChildOfSpan(t *testing.T) {
+	recorder := newRecorder(t)
+
+	ctx, parent := StartFrom(context.Background(), "tid_1234", "parent")
+	_, child := StartFrom(ctx, "tid_1234", "child")
+	End(child, errors.New("S3 err"))
+	_, root := StartFrom(context.Background(), "tid_1234", "root")
+	root.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), TransactionIDKey.String("tid_1234"))
+	assert.Equal(t, "root", spans[1].Name())
+	assert.False(t, spans[1].Parent().IsValid())
+}
+
+func TestDetachKeepsSpanWithoutCancellation(t *testing.T) {
+	recorder := newRecorder(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, parent := StartFrom(ctx, "tid_1234", "request")
+	detached := Detach(ctx)
+	cancel()
+	_, child := StartFrom(detached, "tid_1234", "job")
+	child.End()
+	parent.End()
+
+	assert.NoError(t, detached.Err())
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+func TestInjectAndExtract(t *testing.T) {
+	newRecorder(t)
+
+	ctx, span := StartFrom(context.Background(), "tid_1234", "upload")
+	defer span.End()
+	req, err := http.NewRequest("PUT", "http://localhost/concept/Brand.csv", nil)
+	assert.NoError(t, err)
+	Inject(ctx, req)
+	assert.NotEmpty(t, req.Header.Get("traceparent"))
+
+	_, child := StartFrom(Extract(req), "tid_1234", "server")
+	defer child.End()
+	assert.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID())
+}
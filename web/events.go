@@ -0,0 +1,139 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+)
+
+const (
+	stateInterval    = 500 * time.Millisecond
+	progressInterval = 2 * time.Second
+	//streams end before the write timeout of the server, EventSource clients reconnect to the stream by themselves
+	streamDuration = 50 * time.Second
+	reconnectDelay = 1000
+)
+
+//WorkerProgress is the progress of a worker sent on every progress tick
+type WorkerProgress struct {
+	ConceptType   string        `json:"ConceptType"`
+	Status        concept.State `json:"Status,omitempty"`
+	Count         int           `json:"Count"`
+	Progress      int           `json:"Progress"`
+	RowsPerSecond float64       `json:"RowsPerSecond"`
+}
+
+type jobProgress struct {
+	JobID   string           `json:"JobID"`
+	Workers []WorkerProgress `json:"Workers"`
+}
+
+//GetJobEvents streams a state event with the job for each of its state changes and progress events with the rows read per worker, until the job finished.
+//A job already finished has no events, which is answered with 204 so EventSource clients stop reconnecting. Followers stream the job of the leader replica
+func (handler *RequestHandler) GetJobEvents(writer http.ResponseWriter, request *http.Request) {
+	id := mux.Vars(request)["id"]
+	job, err := handler.findJob(id)
+	if err != nil {
+		handler.failLeaderJobs(writer, request, err)
+		return
+	}
+	if job == nil {
+		problem.Write(writer, http.StatusNotFound, fmt.Sprintf("Job %v not found", id), nil)
+		return
+	}
+	if job.Status == concept.FINISHED {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		problem.Write(writer, http.StatusInternalServerError, "Streaming is not supported", nil)
+		return
+	}
+	tid := transactionidutils.GetTransactionIDFromRequest(request)
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	fmt.Fprintf(writer, "retry: %d\n\n", reconnectDelay)
+
+	send := func(eventType string, v interface{}) bool {
+		data, err := json.Marshal(v)
+		if err == nil {
+			_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", eventType, data)
+		}
+		if err != nil {
+			handler.Log.WithTransactionID(tid).WithError(err).Warnf("Failed to stream events of job %v", id)
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	state := jobState(job)
+	if !send("state", job) {
+		return
+	}
+	last := progressOf(job, nil, 0)
+	lastTick := time.Now()
+	stateTicker := time.NewTicker(stateInterval)
+	defer stateTicker.Stop()
+	progressTicker := time.NewTicker(progressInterval)
+	defer progressTicker.Stop()
+	timeout := time.After(streamDuration)
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-timeout:
+			return
+		case <-stateTicker.C:
+			if job, err = handler.findJob(id); err != nil || job == nil {
+				return
+			}
+			if s := jobState(job); s != state {
+				state = s
+				if !send("state", job) || job.Status == concept.FINISHED {
+					return
+				}
+			}
+		case now := <-progressTicker.C:
+			if job, err = handler.findJob(id); err != nil || job == nil {
+				return
+			}
+			last = progressOf(job, last, now.Sub(lastTick))
+			lastTick = now
+			if !send("progress", &jobProgress{JobID: job.ID, Workers: last}) {
+				return
+			}
+		}
+	}
+}
+
+//jobState summarises what a state event is sent for
+func jobState(job *export.Job) string {
+	state := fmt.Sprintf("%v|%v|%v|%v|%v|%v", job.Status, job.Progress, job.Failed, job.Published, len(job.Files), job.ErrorMessage)
+	for _, w := range job.Workers {
+		state += fmt.Sprintf("|%v:%v", w.ConceptType, w.Status)
+	}
+	return state
+}
+
+func progressOf(job *export.Job, previous []WorkerProgress, elapsed time.Duration) []WorkerProgress {
+	progress := []WorkerProgress{}
+	for i, w := range job.Workers {
+		p := WorkerProgress{ConceptType: w.ConceptType, Status: w.Status, Count: w.Count, Progress: w.Progress}
+		if i < len(previous) && elapsed > 0 {
+			p.RowsPerSecond = float64(w.Progress-previous[i].Progress) / elapsed.Seconds()
+		}
+		progress = append(progress, p)
+	}
+	return progress
+}
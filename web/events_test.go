@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type noopInquirer struct{}
+
+func (noopInquirer) Inquire(_ context.Context, candidates []string, opts concept.Options, tid string) []*concept.Worker {
+	return nil
+}
+
+type noopUpdater struct{}
+
+func (noopUpdater) Upload(_ context.Context, content []byte, fileName, tid string) error {
+	return nil
+}
+
+func newTestServer(handler *RequestHandler) *httptest.Server {
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs/{id}/events", handler.GetJobEvents).Methods(http.MethodGet)
+	return httptest.NewServer(router)
+}
+
+func TestRequestHandler_GetJobEventsStreamsUntilJobFinished(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	inquirer := blockingInquirer{release: make(chan struct{})}
+	exporter := export.NewFullExporter(1, noopUpdater{}, inquirer, export.NewCsvExporter(), log)
+	server := newTestServer(NewRequestHandler(exporter, []string{"Brand"}, log))
+	defer server.Close()
+	job, err := exporter.StartJob(context.Background(), []string{"Brand"}, concept.Options{}, "", "tid_1234")
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(2 * stateInterval)
+		close(inquirer.release)
+	}()
+	resp, err := http.Get(server.URL + "/jobs/" + job.ID + "/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	events := strings.Split(strings.TrimSpace(string(body)), "\n\n")
+	assert.Equal(t, "retry: 1000", events[0])
+	assert.True(t, strings.HasPrefix(events[1], "event: state\ndata: {"))
+	assert.Contains(t, events[1], `"Status":"Running"`)
+	assert.True(t, strings.HasPrefix(events[len(events)-1], "event: state\ndata: {"))
+	assert.Contains(t, events[len(events)-1], `"Status":"Finished"`)
+}
+
+func TestRequestHandler_GetJobEventsUnknownJob(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	exporter := export.NewFullExporter(1, noopUpdater{}, noopInquirer{}, export.NewCsvExporter(), log)
+	server := newTestServer(NewRequestHandler(exporter, []string{"Brand"}, log))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/jobs/job_unknown/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, problem.ContentType, resp.Header.Get("Content-Type"))
+}
+
+func TestRequestHandler_GetJobEventsFinishedJob(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	exporter := export.NewFullExporter(1, noopUpdater{}, noopInquirer{}, export.NewCsvExporter(), log)
+	server := newTestServer(NewRequestHandler(exporter, []string{"Brand"}, log))
+	defer server.Close()
+	job, err := exporter.StartJob(context.Background(), []string{"Brand"}, concept.Options{}, "", "tid_1234")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return !exporter.IsRunningJob() }, time.Second, time.Millisecond)
+
+	resp, err := http.Get(server.URL + "/jobs/" + job.ID + "/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, body)
+}
+
+func TestProgressOf(t *testing.T) {
+	previous := []WorkerProgress{{ConceptType: "Brand", Progress: 100}}
+	job := export.Job{Workers: []*concept.Worker{{ConceptType: "Brand", Count: 1000, Progress: 500, Status: concept.RUNNING}}}
+
+	progress := progressOf(&job, previous, 2*time.Second)
+
+	assert.Equal(t, []WorkerProgress{{ConceptType: "Brand", Status: concept.RUNNING, Count: 1000, Progress: 500, RowsPerSecond: 200}}, progress)
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+//follows tells whether the export jobs are run by another replica holding the lease
+func (handler *RequestHandler) follows() bool {
+	return handler.Elector != nil && !handler.Elector.IsLeader()
+}
+
+//rejectFollower rejects the requests starting jobs on a replica which isn't the leader
+func (handler *RequestHandler) rejectFollower(writer http.ResponseWriter) bool {
+	if !handler.follows() {
+		return false
+	}
+	handler.writeNotLeader(writer)
+	return true
+}
+
+//writeNotLeader answers the requests starting jobs with the replica holding the lease, also when the lease was lost while creating the job
+func (handler *RequestHandler) writeNotLeader(writer http.ResponseWriter) {
+	holder := "holding the lease"
+	if handler.Elector != nil {
+		if l, err := handler.Elector.Leader(); err == nil && l.Holder != "" {
+			holder = l.Holder
+		}
+	}
+	problem.Write(writer, http.StatusServiceUnavailable, fmt.Sprintf("Export jobs are run by the leader replica %v. Please retry later", holder), nil)
+}
+
+//leaderJobs returns the jobs the leader keeps in its lease, as of its last renewal
+func (handler *RequestHandler) leaderJobs() ([]export.Job, error) {
+	l, err := handler.Elector.Leader()
+	if err != nil {
+		return nil, err
+	}
+	jobs := []export.Job{}
+	if len(l.Jobs) != 0 {
+		if err := json.Unmarshal(l.Jobs, &jobs); err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+//jobs returns the jobs of the exporter, those of the leader when following
+func (handler *RequestHandler) jobs() ([]export.Job, error) {
+	if handler.follows() {
+		return handler.leaderJobs()
+	}
+	return handler.Exporter.GetJobs(), nil
+}
+
+//currentJob returns the current job of the exporter, that of the leader when following
+func (handler *RequestHandler) currentJob() (*export.Job, error) {
+	if !handler.follows() {
+		job := handler.Exporter.GetCurrentJob()
+		return &job, nil
+	}
+	jobs, err := handler.leaderJobs()
+	if err != nil {
+		return nil, err
+	}
+	//the current job follows the history and precedes the queued jobs
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if jobs[i].Status != concept.QUEUED {
+			return &jobs[i], nil
+		}
+	}
+	return &export.Job{}, nil
+}
+
+//findJob returns the job of the exporter with the given ID, that of the leader when following, or nil when there's none
+func (handler *RequestHandler) findJob(id string) (*export.Job, error) {
+	if !handler.follows() {
+		job, found := handler.Exporter.GetJob(id)
+		if !found {
+			return nil, nil
+		}
+		return &job, nil
+	}
+	jobs, err := handler.leaderJobs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		if jobs[i].ID == id {
+			return &jobs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+//runsJob tells whether the exporter is running or queuing a job, the leader when following
+func (handler *RequestHandler) runsJob() (bool, error) {
+	if !handler.follows() {
+		return handler.Exporter.IsRunningJob(), nil
+	}
+	jobs, err := handler.leaderJobs()
+	if err != nil {
+		return false, err
+	}
+	for i := range jobs {
+		if jobs[i].Status != concept.FINISHED {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//failLeaderJobs answers a request for the jobs of the leader which couldn't be read
+func (handler *RequestHandler) failLeaderJobs(writer http.ResponseWriter, request *http.Request, err error) {
+	tid := transactionidutils.GetTransactionIDFromRequest(request)
+	handler.Log.WithTransactionID(tid).WithError(err).Warn("Failed to read the jobs of the leader replica")
+	problem.Write(writer, http.StatusServiceUnavailable, "Failed to read the jobs of the leader replica", nil)
+}
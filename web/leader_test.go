@@ -0,0 +1,124 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/lease"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestHandler_FollowerReportsJobOfLeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lease")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := lease.NewFileStore(filepath.Join(dir, "concept-exporter.lease"))
+	log := logger.NewUPPLogger("Test", "PANIC")
+
+	leader := lease.NewElector(store, "pod-1", time.Minute, func() ([]byte, error) {
+		return []byte(`[{"ID":"job_0","Status":"Finished"},{"ID":"job_1","Concepts":["Brand"],"Status":"Running"},{"ID":"job_2","Status":"Queued","Position":1}]`), nil
+	}, log)
+	leader.Start()
+	defer leader.Stop()
+	follower := lease.NewElector(store, "pod-2", time.Minute, func() ([]byte, error) {
+		return []byte(`[]`), nil
+	}, log)
+	follower.Start()
+	defer follower.Stop()
+
+	exporter := export.NewFullExporter(1, noopUpdater{}, noopInquirer{}, export.NewCsvExporter(), log)
+	handler := NewRequestHandler(exporter, []string{"Brand"}, log)
+	handler.Elector = follower
+	handler.Streamer = fixedStreamer{}
+	handler.Streams = make(chan struct{}, 1)
+	router := mux.NewRouter()
+	router.HandleFunc("/export", handler.Export).Methods(http.MethodPost)
+	router.HandleFunc("/export/{conceptType}", handler.GetExportStream).Methods(http.MethodGet)
+	router.HandleFunc("/job", handler.GetJob).Methods(http.MethodGet)
+	router.HandleFunc("/jobs", handler.GetJobs).Methods(http.MethodGet)
+	router.HandleFunc("/jobs/{id}", handler.GetJobByID).Methods(http.MethodGet)
+	router.HandleFunc("/jobs/{id}/events", handler.GetJobEvents).Methods(http.MethodGet)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/job")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var job export.Job
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	assert.Equal(t, "job_1", job.ID)
+	assert.Equal(t, []string{"Brand"}, job.Concepts)
+
+	resp, err = http.Get(server.URL + "/jobs/job_1")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/jobs/job_3")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, problem.ContentType, resp.Header.Get("Content-Type"))
+
+	resp, err = http.Get(server.URL + "/jobs")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var jobs []export.Job
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&jobs))
+	assert.Len(t, jobs, 3)
+	assert.Equal(t, "job_2", jobs[2].ID)
+
+	resp, err = http.Get(server.URL + "/jobs/job_0/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/jobs/job_1/events", nil)
+	assert.NoError(t, err)
+	resp, err = http.DefaultClient.Do(request)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	events := bufio.NewReader(resp.Body)
+	_, err = events.ReadString('\n')
+	assert.NoError(t, err)
+	_, err = events.ReadString('\n')
+	assert.NoError(t, err)
+	state, err := events.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: state\n", state)
+	data, err := events.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Contains(t, data, `"ID":"job_1"`)
+	cancel()
+
+	resp, err = http.Get(server.URL + "/export/Brand")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+
+	resp, err = http.Post(server.URL+"/export", "application/json", nil)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"Export jobs are run by the leader replica pod-1. Please retry later"}`, string(body))
+	assert.Empty(t, exporter.GetJobs())
+}
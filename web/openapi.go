@@ -0,0 +1,341 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Financial-Times/concept-exporter/problem"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+//openAPIDocument describes the service endpoints. The enum of the ConceptType schema is filled with the supported concept types when served
+const openAPIDocument = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Concept Exporter",
+    "description": "Exports concepts from Neo4j to S3",
+    "version": "1"
+  },
+  "components": {
+    "securitySchemes": {
+      "apiKey": {"type": "apiKey", "in": "header", "name": "X-Api-Key"}
+    },
+    "schemas": {
+      "ConceptType": {"type": "string", "enum": []},
+      "Kind": {"type": "string", "enum": ["Concepts", "CoOccurrences", "Trending"], "default": "Concepts"},
+      "CallbackUrls": {
+        "type": "array",
+        "items": {"type": "string", "format": "uri", "pattern": "^https?://"},
+        "description": "URLs notified once the job finished, on one of the allowed callback hosts"
+      },
+      "ExportRequest": {
+        "type": "object",
+        "description": "Version 1 of the export request. Leaving out the concept types runs a full export",
+        "required": ["version"],
+        "additionalProperties": false,
+        "properties": {
+          "version": {"type": "integer", "enum": [1]},
+          "conceptTypes": {"type": "array", "minItems": 1, "uniqueItems": true, "items": {"$ref": "#/components/schemas/ConceptType"}},
+          "kind": {"$ref": "#/components/schemas/Kind"},
+          "format": {"type": "string", "enum": ["csv"], "default": "csv"},
+          "options": {
+            "type": "object",
+            "additionalProperties": false,
+            "properties": {
+              "annotations": {"type": "boolean", "description": "Also exports the annotations of the concepts"},
+              "concordance": {"type": "boolean", "description": "Also exports the source concepts behind the canonical concepts"},
+              "days": {"type": "integer", "minimum": 1, "default": 7, "description": "Window of a Trending export"},
+              "compare": {"type": "boolean", "description": "Compares a Trending export with the previous window"}
+            }
+          },
+          "filters": {
+            "type": "object",
+            "additionalProperties": false,
+            "properties": {
+              "includeUnannotated": {"type": "boolean", "description": "Exports the concepts without annotations too"},
+              "minAnnotations": {"type": "integer", "minimum": 0, "description": "Drops the concepts annotated by fewer content"},
+              "minCount": {"type": "integer", "minimum": 0, "description": "Drops the co-occurrences shared by fewer content"}
+            }
+          },
+          "callbackUrls": {"$ref": "#/components/schemas/CallbackUrls"},
+          "dryRun": {"type": "boolean", "description": "Returns the plan of the export without running it"}
+        }
+      },
+      "LegacyExportRequest": {
+        "type": "object",
+        "description": "Version 0 of the export request, with the options and filters next to the concept types",
+        "additionalProperties": false,
+        "properties": {
+          "version": {"type": "integer", "enum": [0]},
+          "conceptTypes": {
+            "oneOf": [
+              {"type": "string", "description": "Space-separated concept types"},
+              {"type": "array", "minItems": 1, "uniqueItems": true, "items": {"$ref": "#/components/schemas/ConceptType"}}
+            ]
+          },
+          "kind": {"$ref": "#/components/schemas/Kind"},
+          "format": {"type": "string", "enum": ["csv"], "default": "csv"},
+          "annotations": {"type": "boolean"},
+          "concordance": {"type": "boolean"},
+          "days": {"type": "integer", "minimum": 1, "default": 7},
+          "compare": {"type": "boolean"},
+          "includeUnannotated": {"type": "boolean"},
+          "minAnnotations": {"type": "integer", "minimum": 0},
+          "minCount": {"type": "integer", "minimum": 0},
+          "callbackUrls": {"$ref": "#/components/schemas/CallbackUrls"},
+          "dryRun": {"type": "boolean", "description": "Returns the plan of the export without running it"}
+        }
+      },
+      "Worker": {
+        "type": "object",
+        "properties": {
+          "ConceptType": {"type": "string"},
+          "Count": {"type": "integer"},
+          "Progress": {"type": "integer"},
+          "Status": {"type": "string"},
+          "ErrorMessage": {"type": "string"}
+        }
+      },
+      "File": {
+        "type": "object",
+        "properties": {
+          "Name": {"type": "string"},
+          "Key": {"type": "string"},
+          "ExportType": {"type": "string"},
+          "Rows": {"type": "integer"},
+          "Bytes": {"type": "integer"},
+          "SHA256": {"type": "string"}
+        }
+      },
+      "Job": {
+        "type": "object",
+        "properties": {
+          "ID": {"type": "string"},
+          "Concepts": {"type": "array", "items": {"type": "string"}},
+          "Status": {"type": "string", "enum": ["Queued", "Starting", "Running", "Finished"]},
+          "Position": {"type": "integer", "description": "Position of a queued job"},
+          "Options": {"type": "object"},
+          "ConceptWorkers": {"type": "array", "items": {"$ref": "#/components/schemas/Worker"}},
+          "Progress": {"type": "array", "items": {"type": "string"}},
+          "Failed": {"type": "array", "items": {"type": "string"}},
+          "ErrorMessage": {"type": "string"},
+          "StartTime": {"type": "string", "format": "date-time"},
+          "EndTime": {"type": "string", "format": "date-time"},
+          "Files": {"type": "array", "items": {"$ref": "#/components/schemas/File"}},
+          "Published": {"type": "boolean"},
+          "RollbackOf": {"type": "string"},
+          "RetryOf": {"type": "string"},
+          "Callbacks": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Plan": {
+        "type": "object",
+        "description": "What an export would upload, returned by dry runs",
+        "properties": {
+          "DryRun": {"type": "boolean"},
+          "Concepts": {"type": "array", "items": {"type": "string"}},
+          "Options": {"type": "object"},
+          "Files": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "ExportType": {"type": "string"},
+                "Name": {"type": "string"},
+                "Rows": {"type": "integer", "description": "Count of the concepts, for the files of concept types"},
+                "EstimatedBytes": {"type": "integer", "description": "Estimated from the latest file of the export type"},
+                "ErrorMessage": {"type": "string"}
+              }
+            }
+          },
+          "Rows": {"type": "integer"},
+          "EstimatedBytes": {"type": "integer"},
+          "Checks": {
+            "type": "array",
+            "items": {"type": "object", "properties": {"Name": {"type": "string"}, "OK": {"type": "boolean"}, "Message": {"type": "string"}}}
+          },
+          "Ready": {"type": "boolean"}
+        }
+      },
+      "Problem": {
+        "type": "object",
+        "description": "RFC 7807 problem details",
+        "properties": {
+          "type": {"type": "string"},
+          "title": {"type": "string"},
+          "status": {"type": "integer"},
+          "detail": {"type": "string"},
+          "invalid-params": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "name": {"type": "string", "description": "Path of the invalid field in the request body"},
+                "reason": {"type": "string"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "responses": {
+      "Job": {"description": "The job", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Job"}}}},
+      "NotFound": {"description": "No job with the ID", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "Unauthorized": {"description": "Missing or invalid API key", "headers": {"WWW-Authenticate": {"schema": {"type": "string"}}}, "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "TooLarge": {"description": "The signed request body is too large", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "Forbidden": {"description": "The API key misses the scope of the request", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "NotLeader": {"description": "The export jobs are run by another replica", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "QueueFull": {"description": "The job queue is full", "headers": {"Retry-After": {"schema": {"type": "integer"}}}, "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}
+    },
+    "parameters": {
+      "id": {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}
+    }
+  },
+  "security": [{"apiKey": []}],
+  "paths": {
+    "/export": {
+      "post": {
+        "summary": "Runs or queues an export",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {"oneOf": [{"$ref": "#/components/schemas/ExportRequest"}, {"$ref": "#/components/schemas/LegacyExportRequest"}]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"description": "The plan of a dry run", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Plan"}}}},
+          "202": {"$ref": "#/components/responses/Job"},
+          "400": {"description": "Invalid request", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "413": {"$ref": "#/components/responses/TooLarge"},
+          "429": {"$ref": "#/components/responses/QueueFull"},
+          "500": {"description": "The job can't be created", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+          "503": {"$ref": "#/components/responses/NotLeader"}
+        }
+      }
+    },
+    "/export/{conceptType}": {
+      "parameters": [
+        {"name": "conceptType", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/ConceptType"}},
+        {"name": "format", "in": "query", "schema": {"type": "string", "enum": ["csv", "jsonl"], "default": "csv"}}
+      ],
+      "get": {
+        "summary": "Streams the file of a concept type in the response, without running a job nor uploading it",
+        "responses": {
+          "200": {
+            "description": "The file, as an attachment",
+            "headers": {"Content-Disposition": {"schema": {"type": "string"}}},
+            "content": {"text/csv": {}, "application/x-ndjson": {}}
+          },
+          "400": {"description": "Invalid format", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {"description": "Unsupported concept type", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+          "429": {"description": "Too many exports are streamed or previewed", "headers": {"Retry-After": {"schema": {"type": "integer"}}}, "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+          "503": {"description": "Neo4j can't be read, or an export job is running", "headers": {"Retry-After": {"schema": {"type": "integer"}}}, "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}
+        }
+      }
+    },
+    "/job": {
+      "get": {
+        "summary": "Returns the current job",
+        "responses": {"200": {"$ref": "#/components/responses/Job"}, "401": {"$ref": "#/components/responses/Unauthorized"}, "403": {"$ref": "#/components/responses/Forbidden"}}
+      }
+    },
+    "/jobs": {
+      "get": {
+        "summary": "Returns the job history, the current job and the queued jobs",
+        "responses": {
+          "200": {"description": "The jobs", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Job"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"}
+        }
+      }
+    },
+    "/jobs/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/id"}],
+      "get": {
+        "summary": "Returns a job",
+        "responses": {"200": {"$ref": "#/components/responses/Job"}, "401": {"$ref": "#/components/responses/Unauthorized"}, "403": {"$ref": "#/components/responses/Forbidden"}, "404": {"$ref": "#/components/responses/NotFound"}}
+      }
+    },
+    "/jobs/{id}/events": {
+      "parameters": [{"$ref": "#/components/parameters/id"}],
+      "get": {
+        "summary": "Streams the state and progress of a job as server-sent events",
+        "responses": {"200": {"description": "The events. The last one is the state event of the finished job, on which clients close the stream", "content": {"text/event-stream": {}}}, "204": {"description": "The job already finished"}, "401": {"$ref": "#/components/responses/Unauthorized"}, "403": {"$ref": "#/components/responses/Forbidden"}, "404": {"$ref": "#/components/responses/NotFound"}, "500": {"description": "The events can't be streamed", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}}
+      }
+    },
+    "/jobs/{id}/rollback": {
+      "parameters": [{"$ref": "#/components/parameters/id"}],
+      "post": {
+        "summary": "Republishes the files of an earlier job",
+        "responses": {"202": {"$ref": "#/components/responses/Job"}, "400": {"description": "The job can't be rolled back to", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}, "401": {"$ref": "#/components/responses/Unauthorized"}, "403": {"$ref": "#/components/responses/Forbidden"}, "404": {"$ref": "#/components/responses/NotFound"}, "413": {"$ref": "#/components/responses/TooLarge"}, "429": {"$ref": "#/components/responses/QueueFull"}, "503": {"$ref": "#/components/responses/NotLeader"}}
+      }
+    },
+    "/jobs/{id}/retry": {
+      "parameters": [{"$ref": "#/components/parameters/id"}],
+      "post": {
+        "summary": "Exports the failed types of a job again",
+        "responses": {"202": {"$ref": "#/components/responses/Job"}, "400": {"description": "The job can't be retried", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}, "401": {"$ref": "#/components/responses/Unauthorized"}, "403": {"$ref": "#/components/responses/Forbidden"}, "404": {"$ref": "#/components/responses/NotFound"}, "413": {"$ref": "#/components/responses/TooLarge"}, "429": {"$ref": "#/components/responses/QueueFull"}, "503": {"$ref": "#/components/responses/NotLeader"}}
+      }
+    },
+    "/preview/{conceptType}": {
+      "parameters": [
+        {"name": "conceptType", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/ConceptType"}},
+        {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 1000, "default": 10}},
+        {"name": "format", "in": "query", "schema": {"type": "string", "enum": ["csv", "json"], "default": "csv"}}
+      ],
+      "get": {
+        "summary": "Returns the first rows of a concept type as they would be exported, without running a job",
+        "responses": {
+          "200": {"description": "The rows", "content": {"text/csv": {}, "application/json": {"schema": {"type": "array", "items": {"type": "object"}}}}},
+          "400": {"description": "Invalid limit or format", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {"description": "Unsupported concept type", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+          "429": {"description": "Too many exports are streamed or previewed", "headers": {"Retry-After": {"schema": {"type": "integer"}}}, "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+          "503": {"description": "Neo4j can't be read", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}
+        }
+      }
+    },
+    "/schedules": {
+      "get": {
+        "summary": "Returns the scheduled exports",
+        "responses": {"200": {"description": "The schedules", "content": {"application/json": {}}}, "401": {"$ref": "#/components/responses/Unauthorized"}, "403": {"$ref": "#/components/responses/Forbidden"}}
+      }
+    },
+    "/schema": {
+      "get": {
+        "summary": "Returns the data package describing the exported files",
+        "responses": {"200": {"description": "The data package", "content": {"application/json": {}}}, "401": {"$ref": "#/components/responses/Unauthorized"}, "403": {"$ref": "#/components/responses/Forbidden"}}
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "summary": "Returns this document",
+        "responses": {"200": {"description": "The OpenAPI document", "content": {"application/json": {}}}, "401": {"$ref": "#/components/responses/Unauthorized"}, "403": {"$ref": "#/components/responses/Forbidden"}, "500": {"description": "The document can't be read", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}}
+      }
+    }
+  }
+}`
+
+//GetOpenAPI returns the OpenAPI document of the service endpoints
+func (handler *RequestHandler) GetOpenAPI(writer http.ResponseWriter, request *http.Request) {
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(openAPIDocument), &document); err != nil {
+		problem.Write(writer, http.StatusInternalServerError, "Failed to read the OpenAPI document", nil)
+		return
+	}
+	schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	schemas["ConceptType"].(map[string]interface{})["enum"] = handler.ConceptTypes
+	writer.Header().Add("Content-Type", "application/json")
+
+	err := json.NewEncoder(writer).Encode(&document)
+	if err != nil {
+		tid := transactionidutils.GetTransactionIDFromRequest(request)
+		handler.Log.WithTransactionID(tid).WithError(err).Warn("Failed to write the OpenAPI document to response writer")
+	}
+}
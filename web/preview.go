@@ -0,0 +1,83 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Financial-Times/concept-exporter/auth"
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/Financial-Times/concept-exporter/tracing"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPreviewLimit = 10
+	maxPreviewLimit     = 1000
+)
+
+//GetPreview renders the first concepts of a type as the rows of its exported file, without creating a job nor uploading anything.
+//The previews take a token of Streams like the streamed exports
+func (handler *RequestHandler) GetPreview(writer http.ResponseWriter, request *http.Request) {
+	tid := transactionidutils.GetTransactionIDFromRequest(request)
+	conceptType := mux.Vars(request)["conceptType"]
+	if !contains(handler.ConceptTypes, conceptType) {
+		problem.Write(writer, http.StatusNotFound, fmt.Sprintf("%v is not one of the supported concept types %v", conceptType, handler.ConceptTypes), nil)
+		return
+	}
+	if !handler.authorize(writer, request, auth.ExportScope(conceptType)) {
+		return
+	}
+
+	p := &requestParser{}
+	limit := defaultPreviewLimit
+	if l := request.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxPreviewLimit {
+			p.fail("limit", "must be an integer from 1 to %d", maxPreviewLimit)
+		}
+	}
+	format := export.CSVFormat
+	if f := request.URL.Query().Get("format"); f != "" {
+		format = f
+		if format != export.CSVFormat && format != export.JSONFormat {
+			p.fail("format", "must be one of %v", []string{export.CSVFormat, export.JSONFormat})
+		}
+	}
+	if len(p.invalid) != 0 {
+		problem.Write(writer, http.StatusBadRequest, "The preview request is invalid", p.invalid)
+		return
+	}
+
+	if !handler.takeStream(writer) {
+		return
+	}
+	defer handler.releaseStream()
+
+	logEntry := handler.Log.WithTransactionID(tid)
+	opts := concept.Options{}
+	records, err := handler.Previewer.Preview(tracing.Extract(request), conceptType, opts, limit, tid)
+	if err != nil {
+		logEntry.WithError(err).Errorf("Failed to read the preview of %v", conceptType)
+		problem.Write(writer, http.StatusServiceUnavailable, fmt.Sprintf("Failed to read %v concepts", conceptType), nil)
+		return
+	}
+	content, err := export.Preview(conceptType, opts, records, format)
+	if err != nil {
+		logEntry.WithError(err).Errorf("Failed to render the preview of %v", conceptType)
+		problem.Write(writer, http.StatusInternalServerError, fmt.Sprintf("Failed to render %v concepts", conceptType), nil)
+		return
+	}
+	if format == export.JSONFormat {
+		writer.Header().Add("Content-Type", "application/json")
+	} else {
+		writer.Header().Add("Content-Type", "text/csv; charset=utf-8")
+	}
+	if _, err := writer.Write(content); err != nil {
+		logEntry.WithError(err).Warnf("Failed to write the preview of %v to response writer", conceptType)
+	}
+}
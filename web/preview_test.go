@@ -0,0 +1,101 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedPreviewer struct {
+	limit int
+}
+
+func (p *fixedPreviewer) Preview(_ context.Context, conceptType string, opts concept.Options, limit int, tid string) ([]db.Record, error) {
+	p.limit = limit
+	return []db.Record{db.Concept{Id: "http://api.ft.com/things/brand", PrefLabel: "Brand", ApiUrl: "http://api.ft.com/brands/brand"}}, nil
+}
+
+func TestRequestHandler_GetPreview(t *testing.T) {
+	handler := newTestRequestHandler()
+	previewer := &fixedPreviewer{}
+	handler.Previewer = previewer
+	handler.Streams = make(chan struct{}, 1)
+	router := mux.NewRouter()
+	router.HandleFunc("/preview/{conceptType}", handler.GetPreview).Methods(http.MethodGet)
+
+	tests := []struct {
+		name        string
+		url         string
+		status      int
+		contentType string
+		body        string
+		limit       int
+		busy        bool
+	}{
+		{
+			name:        "CSV",
+			url:         "/preview/Brand",
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body:        "id,prefLabel,apiUrl\nhttp://api.ft.com/things/brand,Brand,http://api.ft.com/brands/brand\n",
+			limit:       defaultPreviewLimit,
+		},
+		{
+			name:        "JSON",
+			url:         "/preview/Brand?limit=5&format=json",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `[{"apiUrl":"http://api.ft.com/brands/brand","id":"http://api.ft.com/things/brand","prefLabel":"Brand"}]`,
+			limit:       5,
+		},
+		{
+			name:        "Invalid parameters",
+			url:         "/preview/Brand?limit=0&format=xml",
+			status:      http.StatusBadRequest,
+			contentType: problem.ContentType,
+			body:        `{"type":"about:blank","title":"Bad Request","status":400,"detail":"The preview request is invalid","invalid-params":[{"name":"limit","reason":"must be an integer from 1 to 1000"},{"name":"format","reason":"must be one of [csv json]"}]}` + "\n",
+		},
+		{
+			name:        "Unsupported concept type",
+			url:         "/preview/Genre",
+			status:      http.StatusNotFound,
+			contentType: problem.ContentType,
+			body:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"Genre is not one of the supported concept types [Brand Person Organisation]"}` + "\n",
+		},
+		{
+			name:        "Too many previews",
+			url:         "/preview/Brand",
+			status:      http.StatusTooManyRequests,
+			contentType: problem.ContentType,
+			body:        `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"1 exports are already streamed or previewed. Please retry later"}` + "\n",
+			busy:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			previewer.limit = 0
+			if test.busy {
+				handler.Streams <- struct{}{}
+				defer handler.releaseStream()
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.url, nil))
+			assert.Equal(t, test.status, recorder.Code)
+			assert.Equal(t, test.contentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, test.body, recorder.Body.String())
+			assert.Equal(t, test.limit, previewer.limit)
+			if !test.busy {
+				assert.Empty(t, handler.Streams, "the stream token should be released")
+			}
+		})
+	}
+	assert.Empty(t, handler.Exporter.GetJobs())
+}
@@ -0,0 +1,237 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/problem"
+)
+
+//ExportRequestVersion is the latest version of the export request schema. Version 0, used when the version is missing,
+//is the original flat request with the options and filters next to the concept types, which may be a space-separated string
+const ExportRequestVersion = 1
+
+//ExportRequest is a validated export request
+type ExportRequest struct {
+	ConceptTypes []string
+	Options      concept.Options
+	Callbacks    []string
+	DryRun       bool
+}
+
+//requestParser reads the fields of an export request, collecting every invalid one
+type requestParser struct {
+	invalid []problem.InvalidParam
+}
+
+func (p *requestParser) fail(name, reason string, args ...interface{}) {
+	p.invalid = append(p.invalid, problem.InvalidParam{Name: name, Reason: fmt.Sprintf(reason, args...)})
+}
+
+//take removes the field from the object, so the fields left once the object is read are unknown
+func take(object map[string]json.RawMessage, key string) (json.RawMessage, bool) {
+	raw, ok := object[key]
+	delete(object, key)
+	return raw, ok
+}
+
+func (p *requestParser) object(object map[string]json.RawMessage, key, name string) map[string]json.RawMessage {
+	raw, ok := take(object, key)
+	if !ok {
+		return nil
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &result); err != nil || result == nil {
+		p.fail(name, "must be an object")
+		return nil
+	}
+	return result
+}
+
+func (p *requestParser) boolean(object map[string]json.RawMessage, key, name string, v *bool) {
+	raw, ok := take(object, key)
+	if ok && json.Unmarshal(raw, v) != nil {
+		p.fail(name, "must be a boolean")
+	}
+}
+
+func (p *requestParser) integer(object map[string]json.RawMessage, key, name string, min int, v *int) bool {
+	raw, ok := take(object, key)
+	if !ok {
+		return false
+	}
+	var i int
+	if err := json.Unmarshal(raw, &i); err != nil || i < min {
+		p.fail(name, "must be an integer of at least %d", min)
+		return false
+	}
+	*v = i
+	return true
+}
+
+func (p *requestParser) enum(object map[string]json.RawMessage, key, name string, values []string) (string, bool) {
+	raw, ok := take(object, key)
+	if !ok {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil || !contains(values, s) {
+		p.fail(name, "must be one of %v", values)
+		return "", false
+	}
+	return s, true
+}
+
+func (p *requestParser) unknown(object map[string]json.RawMessage, prefix string) {
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		p.fail(prefix+key, "is not a field of the export request")
+	}
+}
+
+func (p *requestParser) conceptTypes(raw json.RawMessage, version int, supported []string) []string {
+	var candidates []string
+	if err := json.Unmarshal(raw, &candidates); err != nil {
+		var s string
+		if version != 0 || json.Unmarshal(raw, &s) != nil {
+			p.fail("conceptTypes", "must be an array of concept types")
+			return nil
+		}
+		candidates = strings.Fields(s)
+	}
+	if len(candidates) == 0 {
+		p.fail("conceptTypes", "must not be empty. Leave it out for a full export")
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if !contains(supported, candidate) {
+			p.fail("conceptTypes", "%v is not one of the supported concept types %v", candidate, supported)
+		} else if seen[candidate] {
+			p.fail("conceptTypes", "%v is listed more than once", candidate)
+		}
+		seen[candidate] = true
+	}
+	return candidates
+}
+
+//callbacks reads the callback URLs, which must point to one of the allowed hosts, so a request can't make the service post to any internal endpoint
+func (p *requestParser) callbacks(raw json.RawMessage, hosts []string) []string {
+	var callbacks []string
+	if err := json.Unmarshal(raw, &callbacks); err != nil {
+		p.fail("callbackUrls", "must be an array of URLs")
+		return nil
+	}
+	for _, callback := range callbacks {
+		parsed, err := url.Parse(callback)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			p.fail("callbackUrls", "%v is not an absolute HTTP URL", callback)
+		} else if !allowsHost(hosts, parsed) {
+			p.fail("callbackUrls", "%v is not on one of the allowed callback hosts %v", callback, hosts)
+		}
+	}
+	return callbacks
+}
+
+//allowsHost tells whether the host of the URL is allowed, with or without its port
+func allowsHost(hosts []string, u *url.URL) bool {
+	for _, host := range hosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+//options reads the options of the export, from the options object of the request since version 1
+func (p *requestParser) options(object map[string]json.RawMessage, prefix string, opts *concept.Options) {
+	p.boolean(object, "annotations", prefix+"annotations", &opts.Annotations)
+	p.boolean(object, "concordance", prefix+"concordance", &opts.Concordance)
+	p.integer(object, "days", prefix+"days", 1, &opts.Days)
+	p.boolean(object, "compare", prefix+"compare", &opts.Compare)
+}
+
+//filters reads what restricts the exported rows, from the filters object of the request since version 1
+func (p *requestParser) filters(object map[string]json.RawMessage, prefix string, opts *concept.Options) {
+	p.boolean(object, "includeUnannotated", prefix+"includeUnannotated", &opts.IncludeUnannotated)
+	p.integer(object, "minAnnotations", prefix+"minAnnotations", 0, &opts.MinAnnotations)
+	p.integer(object, "minCount", prefix+"minCount", 0, &opts.MinCount)
+}
+
+//validate checks the options read with the rules shared by every job, e.g. that they apply to the kind of the export. Version 0 names the options without their object
+func (p *requestParser) validate(opts *concept.Options, version int) {
+	for _, e := range opts.Validate() {
+		name := e.Name
+		if version == 0 {
+			name = name[strings.Index(name, ".")+1:]
+		}
+		p.invalid = append(p.invalid, problem.InvalidParam{Name: name, Reason: e.Reason})
+	}
+}
+
+//parseExportRequest validates the body of an export request. An empty body is a full export
+func (handler *RequestHandler) parseExportRequest(body []byte) (ExportRequest, []problem.InvalidParam) {
+	result := ExportRequest{ConceptTypes: handler.ConceptTypes}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return result, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return result, []problem.InvalidParam{{Name: "", Reason: "the body must be a JSON object"}}
+	}
+
+	p := &requestParser{}
+	version := 0
+	if raw, ok := take(fields, "version"); ok {
+		//the fields of a request of an unknown version are still read, as the latest version, so every invalid one is listed
+		if json.Unmarshal(raw, &version) != nil || version < 0 || version > ExportRequestVersion {
+			p.fail("version", "must be an integer from 0 to %d", ExportRequestVersion)
+			version = ExportRequestVersion
+		}
+	}
+	if raw, ok := take(fields, "conceptTypes"); ok {
+		result.ConceptTypes = p.conceptTypes(raw, version, handler.ConceptTypes)
+	}
+	if kind, ok := p.enum(fields, "kind", "kind", []string{string(concept.CONCEPTS), string(concept.COOCCURRENCES), string(concept.TRENDING)}); ok {
+		result.Options.Kind = concept.Kind(kind)
+	}
+	p.enum(fields, "format", "format", []string{handler.Exporter.Exporter.Format()})
+	if raw, ok := take(fields, "callbackUrls"); ok {
+		result.Callbacks = p.callbacks(raw, handler.CallbackHosts)
+	}
+	p.boolean(fields, "dryRun", "dryRun", &result.DryRun)
+	if version == 0 {
+		p.options(fields, "", &result.Options)
+		p.filters(fields, "", &result.Options)
+	} else {
+		if options := p.object(fields, "options", "options"); options != nil {
+			p.options(options, "options.", &result.Options)
+			p.unknown(options, "options.")
+		}
+		if filters := p.object(fields, "filters", "filters"); filters != nil {
+			p.filters(filters, "filters.", &result.Options)
+			p.unknown(filters, "filters.")
+		}
+	}
+	p.unknown(fields, "")
+	p.validate(&result.Options, version)
+	return result, p.invalid
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,342 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRequestHandler() *RequestHandler {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	exporter := export.NewFullExporter(1, noopUpdater{}, noopInquirer{}, export.NewCsvExporter(), log)
+	handler := NewRequestHandler(exporter, []string{"Brand", "Person", "Organisation"}, log)
+	handler.CallbackHosts = []string{"example.com"}
+	return handler
+}
+
+//exportRequestTests are the fixtures of the export requests, checked against the parser and the OpenAPI document.
+//beyondSchema marks the requests only invalid by rules the document can't express, e.g. the options of another kind
+var exportRequestTests = []struct {
+	name         string
+	body         string
+	request      ExportRequest
+	invalid      []problem.InvalidParam
+	beyondSchema bool
+}{
+	{
+		name:    "Empty body",
+		body:    "",
+		request: ExportRequest{ConceptTypes: []string{"Brand", "Person", "Organisation"}},
+	},
+	{
+		name:    "Version 0",
+		body:    `{"conceptTypes":"Brand Person", "includeUnannotated": true, "minAnnotations": 5, "annotations": true}`,
+		request: ExportRequest{ConceptTypes: []string{"Brand", "Person"}, Options: concept.Options{ReadOptions: db.ReadOptions{IncludeUnannotated: true, MinAnnotations: 5}, Annotations: true}},
+	},
+	{
+		name:    "Version 0 trending with default days",
+		body:    `{"kind":"Trending", "conceptTypes":["Person"]}`,
+		request: ExportRequest{ConceptTypes: []string{"Person"}, Options: concept.Options{Kind: concept.TRENDING, Days: 7}},
+	},
+	{
+		name: "Version 1",
+		body: `{"version": 1, "conceptTypes": ["Organisation", "Person"], "kind": "Trending", "format": "csv",
+			"options": {"days": 1, "compare": true}, "callbackUrls": ["https://example.com/exports"]}`,
+		request: ExportRequest{
+			ConceptTypes: []string{"Organisation", "Person"},
+			Options:      concept.Options{Kind: concept.TRENDING, Days: 1, Compare: true},
+			Callbacks:    []string{"https://example.com/exports"},
+		},
+	},
+	{
+		name:    "Version 1 co-occurrences",
+		body:    `{"version": 1, "conceptTypes": ["Organisation", "Person"], "kind": "CoOccurrences", "filters": {"minCount": 3}}`,
+		request: ExportRequest{ConceptTypes: []string{"Organisation", "Person"}, Options: concept.Options{Kind: concept.COOCCURRENCES, MinCount: 3}},
+	},
+	{
+		name: "Options of another kind",
+		body: `{"version": 1, "kind": "CoOccurrences", "options": {"annotations": true, "concordance": true, "days": 3},
+			"filters": {"includeUnannotated": true, "minAnnotations": 2, "minCount": 1}}`,
+		invalid: []problem.InvalidParam{
+			{Name: "options.annotations", Reason: "only applies to the Concepts kind"},
+			{Name: "options.concordance", Reason: "only applies to the Concepts kind"},
+			{Name: "options.days", Reason: "only applies to the Trending kind"},
+			{Name: "filters.includeUnannotated", Reason: "only applies to the Concepts kind"},
+			{Name: "filters.minAnnotations", Reason: "only applies to the Concepts kind"},
+		},
+		beyondSchema: true,
+	},
+	{
+		name: "Version 0 trending with concept filters",
+		body: `{"kind":"Trending", "conceptTypes":"Person", "minAnnotations": 1, "minCount": 2}`,
+		invalid: []problem.InvalidParam{
+			{Name: "minAnnotations", Reason: "only applies to the Concepts kind"},
+			{Name: "minCount", Reason: "only applies to the CoOccurrences kind"},
+		},
+		beyondSchema: true,
+	},
+	{
+		name:    "Invalid JSON",
+		body:    `{"conceptTypes":`,
+		request: ExportRequest{ConceptTypes: []string{"Brand", "Person", "Organisation"}},
+		invalid: []problem.InvalidParam{{Name: "", Reason: "the body must be a JSON object"}},
+	},
+	{
+		name: "Unsupported version",
+		body: `{"version": 2, "conceptTypes": "Brand", "days": 3}`,
+		invalid: []problem.InvalidParam{
+			{Name: "version", Reason: "must be an integer from 0 to 1"},
+			{Name: "conceptTypes", Reason: "must be an array of concept types"},
+			{Name: "days", Reason: "is not a field of the export request"},
+		},
+	},
+	{
+		name: "Every invalid field",
+		body: `{"version": 1, "conceptTypes": "Brand", "kind": "Pairs", "format": "xml", "options": {"days": 0, "compare": true, "concordance": "yes"},
+			"filters": {"minAnnotations": -1, "includeUnannotated": true, "minScore": 2}, "callbackUrls": ["ftp://example.com"], "annotations": true}`,
+		request: ExportRequest{Callbacks: []string{"ftp://example.com"}, Options: concept.Options{Compare: true, ReadOptions: db.ReadOptions{IncludeUnannotated: true}}},
+		invalid: []problem.InvalidParam{
+			{Name: "conceptTypes", Reason: "must be an array of concept types"},
+			{Name: "kind", Reason: "must be one of [Concepts CoOccurrences Trending]"},
+			{Name: "format", Reason: "must be one of [csv]"},
+			{Name: "callbackUrls", Reason: "ftp://example.com is not an absolute HTTP URL"},
+			{Name: "options.concordance", Reason: "must be a boolean"},
+			{Name: "options.days", Reason: "must be an integer of at least 1"},
+			{Name: "filters.minAnnotations", Reason: "must be an integer of at least 0"},
+			{Name: "filters.minScore", Reason: "is not a field of the export request"},
+			{Name: "annotations", Reason: "is not a field of the export request"},
+			{Name: "options.compare", Reason: "only applies to the Trending kind"},
+		},
+	},
+	{
+		name:         "Callback on another host",
+		body:         `{"callbackUrls": ["https://example.com:8443/exports", "http://169.254.169.254/latest/meta-data"]}`,
+		invalid:      []problem.InvalidParam{{Name: "callbackUrls", Reason: "http://169.254.169.254/latest/meta-data is not on one of the allowed callback hosts [example.com]"}},
+		beyondSchema: true,
+	},
+	{
+		name:    "Unsupported and duplicate concept types",
+		body:    `{"conceptTypes":"Brand Genre Brand"}`,
+		request: ExportRequest{ConceptTypes: []string{"Brand", "Genre", "Brand"}},
+		invalid: []problem.InvalidParam{
+			{Name: "conceptTypes", Reason: "Genre is not one of the supported concept types [Brand Person Organisation]"},
+			{Name: "conceptTypes", Reason: "Brand is listed more than once"},
+		},
+		beyondSchema: true,
+	},
+	{
+		name:    "Empty concept types",
+		body:    `{"conceptTypes":[]}`,
+		invalid: []problem.InvalidParam{{Name: "conceptTypes", Reason: "must not be empty. Leave it out for a full export"}},
+	},
+}
+
+func TestRequestHandler_ParseExportRequest(t *testing.T) {
+	handler := newTestRequestHandler()
+	for _, test := range exportRequestTests {
+		t.Run(test.name, func(t *testing.T) {
+			request, invalid := handler.parseExportRequest([]byte(test.body))
+			assert.Equal(t, test.invalid, invalid)
+			if len(test.invalid) == 0 {
+				assert.Equal(t, test.request, request)
+			}
+		})
+	}
+}
+
+func TestRequestHandler_ExportInvalidRequest(t *testing.T) {
+	handler := newTestRequestHandler()
+	recorder := httptest.NewRecorder()
+	handler.Export(recorder, httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(`{"conceptTypes":"Brand Genre","minCount":-1}`)))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "The export request is invalid",
+		"invalid-params": [
+			{"name": "conceptTypes", "reason": "Genre is not one of the supported concept types [Brand Person Organisation]"},
+			{"name": "minCount", "reason": "must be an integer of at least 0"}
+		]
+	}`, recorder.Body.String())
+	assert.Empty(t, handler.Exporter.GetJobs())
+}
+
+func TestRequestHandler_GetOpenAPI(t *testing.T) {
+	handler := newTestRequestHandler()
+	recorder := httptest.NewRecorder()
+	handler.GetOpenAPI(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var document struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]struct {
+				Enum []string `json:"enum"`
+			} `json:"schemas"`
+		} `json:"components"`
+		Paths map[string]interface{} `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &document))
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+	assert.Equal(t, []string{"Brand", "Person", "Organisation"}, document.Components.Schemas["ConceptType"].Enum)
+	assert.Contains(t, document.Paths, "/export")
+}
+
+func TestRequestHandler_ExportDryRun(t *testing.T) {
+	handler := newTestRequestHandler()
+	recorder := httptest.NewRecorder()
+	handler.Export(recorder, httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(`{"version":1,"conceptTypes":["Brand"],"options":{"concordance":true},"dryRun":true}`)))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var plan export.Plan
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &plan))
+	assert.True(t, plan.DryRun)
+	assert.True(t, plan.Ready)
+	assert.Equal(t, []export.PlannedFile{{ExportType: "Brand", Name: "Brand.csv"}, {ExportType: concept.Concordance, Name: "Concordance.csv"}}, plan.Files)
+	assert.Empty(t, handler.Exporter.GetJobs())
+}
+
+//validateSchema checks the value against the subset of JSON schema used by the OpenAPI document, returning the paths of the invalid fields
+func validateSchema(document, schema map[string]interface{}, value interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved := document
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			resolved = resolved[key].(map[string]interface{})
+		}
+		return validateSchema(document, resolved, value, path)
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, s := range oneOf {
+			if len(validateSchema(document, s.(map[string]interface{}), value, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%v: matches %d schemas of oneOf", path, matches)}
+		}
+		return nil
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || reflect.DeepEqual(e, value)
+		}
+		if !found {
+			return []string{path + ": not in enum"}
+		}
+	}
+
+	var errs []string
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{path + ": not an object"}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, key := range required {
+				if _, ok := object[key.(string)]; !ok {
+					errs = append(errs, fmt.Sprintf("%v%v: missing", path, key))
+				}
+			}
+		}
+		for key, v := range object {
+			property, ok := properties[key].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == false {
+					errs = append(errs, path+key+": not a property")
+				}
+				continue
+			}
+			errs = append(errs, validateSchema(document, property, v, path+key+".")...)
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []string{path + ": not an array"}
+		}
+		if min, ok := schema["minItems"].(float64); ok && float64(len(array)) < min {
+			errs = append(errs, path+": too few items")
+		}
+		for i, item := range array {
+			for _, other := range array[:i] {
+				if schema["uniqueItems"] == true && reflect.DeepEqual(item, other) {
+					errs = append(errs, path+": duplicate items")
+				}
+			}
+			if items, ok := schema["items"].(map[string]interface{}); ok {
+				errs = append(errs, validateSchema(document, items, item, path)...)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return []string{path + ": not a string"}
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			errs = append(errs, path+": does not match the pattern")
+		}
+	case "integer":
+		i, ok := value.(float64)
+		if !ok || i != math.Trunc(i) {
+			return []string{path + ": not an integer"}
+		}
+		if min, ok := schema["minimum"].(float64); ok && i < min {
+			errs = append(errs, path+": below the minimum")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{path + ": not a boolean"}
+		}
+	}
+	return errs
+}
+
+func TestRequestHandler_OpenAPIDocumentDescribesExportRequests(t *testing.T) {
+	handler := newTestRequestHandler()
+	recorder := httptest.NewRecorder()
+	handler.GetOpenAPI(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var document map[string]interface{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &document))
+	post := document["paths"].(map[string]interface{})["/export"].(map[string]interface{})["post"].(map[string]interface{})
+	content := post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})
+	schema := content["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+
+	for _, test := range exportRequestTests {
+		if test.body == "" {
+			continue
+		}
+		t.Run(test.name, func(t *testing.T) {
+			var body interface{}
+			var errs []string
+			if err := json.Unmarshal([]byte(test.body), &body); err != nil {
+				errs = []string{err.Error()}
+			} else {
+				errs = validateSchema(document, schema, body, "")
+			}
+			if len(test.invalid) == 0 || test.beyondSchema {
+				assert.Empty(t, errs, "the document rejects a request the service accepts")
+			} else {
+				assert.NotEmpty(t, errs, "the document accepts a request the service rejects")
+			}
+		})
+	}
+}
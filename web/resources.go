@@ -1,22 +1,46 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"io/ioutil"
 	"net/http"
-	"strings"
+	"strconv"
 
+	"github.com/Financial-Times/concept-exporter/auth"
+	"github.com/Financial-Times/concept-exporter/concept"
 	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/lease"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/Financial-Times/concept-exporter/schedule"
+	"github.com/Financial-Times/concept-exporter/tracing"
 	logger "github.com/Financial-Times/go-logger/v2"
 	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	//queueFullRetryAfter is the seconds a request rejected by a full job queue should be retried after
+	queueFullRetryAfter = 60
 )
 
 type RequestHandler struct {
 	Exporter     *export.FullExporter
+	Scheduler    *schedule.Scheduler
+	Elector      *lease.Elector
+	Auth         *auth.Authenticator
+	Previewer    concept.Previewer
+	Streamer     concept.Streamer
 	ConceptTypes []string
 	Log          *logger.UPPLogger
+	//Streams holds a token per export streamed by GetExportStream or previewed by GetPreview. Its capacity is how many are read at the same time
+	Streams chan struct{}
+	//CallbackHosts are the hosts the callback URLs of export requests may point to
+	CallbackHosts []string
 }
 
 func NewRequestHandler(fullExporter *export.FullExporter, conceptTypes []string, log *logger.UPPLogger) *RequestHandler {
@@ -27,12 +51,16 @@ func NewRequestHandler(fullExporter *export.FullExporter, conceptTypes []string,
 	}
 }
 
+//GetJob returns the current job, that of the leader replica when following
 func (handler *RequestHandler) GetJob(writer http.ResponseWriter, request *http.Request) {
+	job, err := handler.currentJob()
+	if err != nil {
+		handler.failLeaderJobs(writer, request, err)
+		return
+	}
 	writer.Header().Add("Content-Type", "application/json")
 
-	job := handler.Exporter.GetCurrentJob()
-
-	err := json.NewEncoder(writer).Encode(&job)
+	err = json.NewEncoder(writer).Encode(job)
 	if err != nil {
 		msg := fmt.Sprintf(`Failed to write job %v to response writer: "%v"`, job.ID, err)
 		tid := transactionidutils.GetTransactionIDFromRequest(request)
@@ -42,86 +70,231 @@ func (handler *RequestHandler) GetJob(writer http.ResponseWriter, request *http.
 	}
 }
 
-func (handler *RequestHandler) Export(writer http.ResponseWriter, request *http.Request) {
-	tid := transactionidutils.GetTransactionIDFromRequest(request)
+//GetJobs returns the job history, the current job and the queued jobs, those of the leader replica when following
+func (handler *RequestHandler) GetJobs(writer http.ResponseWriter, request *http.Request) {
+	jobs, err := handler.jobs()
+	if err != nil {
+		handler.failLeaderJobs(writer, request, err)
+		return
+	}
+	writer.Header().Add("Content-Type", "application/json")
 
-	if handler.Exporter.IsRunningJob() {
-		http.Error(writer, "There are already running export jobs. Please wait them to finish", http.StatusBadRequest)
+	err = json.NewEncoder(writer).Encode(&jobs)
+	if err != nil {
+		tid := transactionidutils.GetTransactionIDFromRequest(request)
+		handler.Log.WithTransactionID(tid).WithError(err).Warn("Failed to write jobs to response writer")
+	}
+}
+
+func (handler *RequestHandler) GetJobByID(writer http.ResponseWriter, request *http.Request) {
+	id := mux.Vars(request)["id"]
+	job, err := handler.findJob(id)
+	if err != nil {
+		handler.failLeaderJobs(writer, request, err)
 		return
 	}
-	candidates, errMsg := handler.getCandidateConceptTypes(request, tid)
-	if len(candidates) == 0 {
-		http.Error(writer, "No valid candidate concept types in the request", http.StatusBadRequest)
+	if job == nil {
+		problem.Write(writer, http.StatusNotFound, fmt.Sprintf("Job %v not found", id), nil)
 		return
 	}
-	job := handler.Exporter.CreateJob(candidates, errMsg)
-	go handler.Exporter.RunFullExport(tid)
-	writer.WriteHeader(http.StatusAccepted)
 	writer.Header().Add("Content-Type", "application/json")
 
-	err := json.NewEncoder(writer).Encode(&job)
+	err = json.NewEncoder(writer).Encode(job)
 	if err != nil {
-		msg := fmt.Sprintf(`Failed to write job %v to response writer: "%v"`, job.ID, err)
-		handler.Log.WithTransactionID(tid).Warnf(msg)
-		fmt.Fprintf(writer, "{\"ID\": \"%v\"}", job.ID)
-		return
+		tid := transactionidutils.GetTransactionIDFromRequest(request)
+		handler.Log.WithTransactionID(tid).WithError(err).Warnf("Failed to write job %v to response writer", job.ID)
 	}
 }
 
-func (handler *RequestHandler) getCandidateConceptTypes(request *http.Request, tid string) (candidates []string, errMsg string) {
-	candidates = extractCandidateConceptTypesFromRequest(request, handler.Log.WithTransactionID(tid))
-	if len(candidates) != 0 {
-		var unsupported []string
-		for i, cand := range candidates {
-			found := false
-			for _, cType := range handler.ConceptTypes {
-				if cand == cType {
-					found = true
-					break
-				}
-			}
-			if !found {
-				unsupported = append(unsupported, cand)
-				candidates = append(candidates[:i], candidates[i+1:]...)
+//Rollback republishes the archived files of an earlier successful job as the current export
+func (handler *RequestHandler) Rollback(writer http.ResponseWriter, request *http.Request) {
+	handler.startJobOf(writer, request, handler.Exporter.CreateRollbackJob, auth.RollbackScope, "rolled back to")
+}
+
+//Retry exports the failed types of a job again
+func (handler *RequestHandler) Retry(writer http.ResponseWriter, request *http.Request) {
+	handler.startJobOf(writer, request, handler.Exporter.CreateRetryJob, auth.RetryScope, "retried")
+}
+
+func (handler *RequestHandler) writePlan(writer http.ResponseWriter, plan export.Plan, tid string) {
+	writer.Header().Add("Content-Type", "application/json")
+
+	err := json.NewEncoder(writer).Encode(&plan)
+	if err != nil {
+		handler.Log.WithTransactionID(tid).WithError(err).Warn("Failed to write plan to response writer")
+	}
+}
+
+//authorize rejects the request unless the API key it was authenticated with has the scope, when authentication is enabled
+func (handler *RequestHandler) authorize(writer http.ResponseWriter, request *http.Request, scope string) bool {
+	if d := handler.deny(request, scope); d != nil {
+		problem.Write(writer, d.status, d.detail, nil)
+		return false
+	}
+	return true
+}
+
+//denial is the problem answering a request whose API key misses a scope
+type denial struct {
+	status int
+	detail string
+}
+
+func (d *denial) Error() string {
+	return d.detail
+}
+
+//deny tells why the API key of the request isn't allowed the scope, if it isn't
+func (handler *RequestHandler) deny(request *http.Request, scope string) *denial {
+	if handler.Auth == nil {
+		return nil
+	}
+	key, ok := auth.FromContext(request.Context())
+	if !ok {
+		return &denial{status: http.StatusUnauthorized, detail: "Unauthenticated request"}
+	}
+	if !key.Can(scope) {
+		return &denial{status: http.StatusForbidden, detail: fmt.Sprintf("API key %v is not allowed the %v scope", key.ID, scope)}
+	}
+	return nil
+}
+
+//exportScopes authorizes the concept types of a job derived from the job of the request, needing the scopes exporting them like the requested jobs
+func (handler *RequestHandler) exportScopes(request *http.Request) export.Authorize {
+	return func(concepts []string) error {
+		for _, c := range concepts {
+			if d := handler.deny(request, auth.ExportScope(c)); d != nil {
+				return d
 			}
 		}
-		if len(unsupported) != 0 {
-			errMsg = fmt.Sprintf("There are unsupported concept types within the candidates: %v", unsupported)
-		}
-		if len(candidates) == 0 {
-			return
-		}
+		return nil
+	}
+}
+
+//startJobOf creates a job derived from the job of the request, run like the requested jobs right away or once the queued ones finished.
+//Besides the scope of the action, the API key needs the scopes exporting the concept types of the job
+func (handler *RequestHandler) startJobOf(writer http.ResponseWriter, request *http.Request, create func(ctx context.Context, jobID, tid string, authorize export.Authorize) (export.Job, error), scope, action string) {
+	tid := transactionidutils.GetTransactionIDFromRequest(request)
+
+	if !handler.authorize(writer, request, scope) {
+		return
+	}
+	if handler.rejectFollower(writer) {
+		return
+	}
+	id := mux.Vars(request)["id"]
+	job, err := create(tracing.Extract(request), id, tid, handler.exportScopes(request))
+	var d *denial
+	if errors.As(err, &d) {
+		problem.Write(writer, d.status, d.detail, nil)
+		return
+	}
+	if err == export.ErrQueueFull {
+		writer.Header().Set("Retry-After", strconv.Itoa(queueFullRetryAfter))
+		problem.Write(writer, http.StatusTooManyRequests, "The job queue is full. Please retry later", nil)
+		return
+	}
+	if err == export.ErrNotLeader {
+		handler.writeNotLeader(writer)
+		return
 	}
-	if len(candidates) == 0 {
-		handler.Log.WithTransactionID(tid).Infof("Content type candidates are empty. Using all supported ones: %v", handler.ConceptTypes)
-		candidates = handler.ConceptTypes
+	if err == export.ErrJobNotFound {
+		problem.Write(writer, http.StatusNotFound, fmt.Sprintf("Job %v not found", id), nil)
+		return
+	}
+	if err != nil {
+		problem.Write(writer, http.StatusBadRequest, fmt.Sprintf("Job %v cannot be %v: %v", id, action, err), nil)
+		return
+	}
+	handler.Log.WithTransactionID(tid).Infof("Job %v is %v by job %v", id, action, job.ID)
+	writer.Header().Add("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusAccepted)
+
+	err = json.NewEncoder(writer).Encode(&job)
+	if err != nil {
+		handler.Log.WithTransactionID(tid).WithError(err).Warnf("Failed to write job %v to response writer", job.ID)
+	}
+}
+
+func (handler *RequestHandler) GetSchedules(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Add("Content-Type", "application/json")
+
+	schedules := []schedule.Schedule{}
+	if handler.Scheduler != nil {
+		schedules = handler.Scheduler.Schedules()
+	}
+
+	err := json.NewEncoder(writer).Encode(&schedules)
+	if err != nil {
+		tid := transactionidutils.GetTransactionIDFromRequest(request)
+		handler.Log.WithTransactionID(tid).WithError(err).Warn("Failed to write schedules to response writer")
+	}
+}
+
+func (handler *RequestHandler) GetSchema(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Add("Content-Type", "application/json")
+
+	dataPackage := handler.Exporter.Exporter.DataPackage(handler.ConceptTypes, concept.Options{})
+
+	err := json.NewEncoder(writer).Encode(&dataPackage)
+	if err != nil {
+		tid := transactionidutils.GetTransactionIDFromRequest(request)
+		handler.Log.WithTransactionID(tid).WithError(err).Warn("Failed to write schema to response writer")
 	}
-	return
 }
 
-func extractCandidateConceptTypesFromRequest(request *http.Request, log *logger.LogEntry) (candidates []string) {
-	var result map[string]interface{}
+func (handler *RequestHandler) Export(writer http.ResponseWriter, request *http.Request) {
+	tid := transactionidutils.GetTransactionIDFromRequest(request)
+	ctx, span := tracing.StartFrom(tracing.Extract(request), tid, "RequestHandler.Export")
+	defer span.End()
+
 	body, err := ioutil.ReadAll(request.Body)
 	if err != nil {
-		log.WithError(err).Error("no valid POST body found, thus no candidate concept types to export")
+		problem.Write(writer, http.StatusBadRequest, "Failed to read the request body", nil)
 		return
 	}
-
-	if err = json.Unmarshal(body, &result); err != nil {
-		log.WithError(err).Error("no valid JSON body found, thus no candidate concept types to export")
+	exportRequest, invalid := handler.parseExportRequest(body)
+	if len(invalid) != 0 {
+		problem.Write(writer, http.StatusBadRequest, "The export request is invalid", invalid)
 		return
 	}
-	log.Debugf("Parsing request body: %v", result)
-	cTypes, ok := result["conceptTypes"]
-	if !ok {
-		log.Infof("no conceptTypes field found in the JSON body, thus no candidate concept types to export.")
+	candidates := exportRequest.ConceptTypes
+	for _, candidate := range candidates {
+		if !handler.authorize(writer, request, auth.ExportScope(candidate)) {
+			return
+		}
+	}
+	if exportRequest.DryRun {
+		handler.writePlan(writer, handler.Exporter.Plan(ctx, candidates, exportRequest.Options, tid), tid)
 		return
 	}
-	cTypesString, ok := cTypes.(string)
-	if ok {
-		candidates = strings.Split(cTypesString, " ")
-	} else {
-		log.WithError(err).Error("the conceptTypes field found in JSON body is not a string as expected.")
+	if handler.rejectFollower(writer) {
+		return
+	}
+	job, err := handler.Exporter.QueueJob(ctx, candidates, exportRequest.Options, exportRequest.Callbacks, "", tid)
+	if err == export.ErrQueueFull {
+		writer.Header().Set("Retry-After", strconv.Itoa(queueFullRetryAfter))
+		problem.Write(writer, http.StatusTooManyRequests, "The job queue is full. Please retry later", nil)
+		return
+	}
+	if err == export.ErrNotLeader {
+		handler.writeNotLeader(writer)
+		return
+	}
+	if err != nil {
+		handler.Log.WithTransactionID(tid).WithError(err).Error("Failed to create the export job")
+		problem.Write(writer, http.StatusInternalServerError, fmt.Sprintf("The export job cannot be created: %v", err), nil)
+		return
+	}
+	span.SetAttributes(attribute.String("job_id", job.ID))
+	writer.Header().Add("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusAccepted)
+
+	err = json.NewEncoder(writer).Encode(&job)
+	if err != nil {
+		msg := fmt.Sprintf(`Failed to write job %v to response writer: "%v"`, job.ID, err)
+		handler.Log.WithTransactionID(tid).Warnf(msg)
+		fmt.Fprintf(writer, "{\"ID\": \"%v\"}", job.ID)
+		return
 	}
-	return
 }
@@ -0,0 +1,135 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/auth"
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestHandler_ExportChecksScopesOfConceptTypes(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	exporter := export.NewFullExporter(1, noopUpdater{}, noopInquirer{}, export.NewCsvExporter(), log)
+	handler := NewRequestHandler(exporter, []string{"Brand", "Person"}, log)
+	handler.Auth = auth.NewAuthenticator([]auth.Key{{ID: "brands", Secret: "secret", Scopes: []string{auth.ExportScope("Brand")}}})
+	server := handler.Auth.Middleware(http.HandlerFunc(handler.Export))
+
+	request := httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(`{"conceptTypes":"Brand Person"}`))
+	request.Header.Set(auth.APIKeyHeader, "secret")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Forbidden","status":403,"detail":"API key brands is not allowed the export:Person scope"}`, recorder.Body.String())
+	assert.Empty(t, exporter.GetJobs())
+
+	request = httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(`{"conceptTypes":"Brand"}`))
+	request.Header.Set(auth.APIKeyHeader, "secret")
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+}
+
+type blockingInquirer struct {
+	release chan struct{}
+}
+
+func (i blockingInquirer) Inquire(_ context.Context, candidates []string, opts concept.Options, tid string) []*concept.Worker {
+	<-i.release
+	return nil
+}
+
+func TestRequestHandler_ExportQueueFull(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	inquirer := blockingInquirer{release: make(chan struct{})}
+	defer close(inquirer.release)
+	exporter := export.NewFullExporter(1, noopUpdater{}, inquirer, export.NewCsvExporter(), log)
+	exporter.QueueSize = 0
+	handler := NewRequestHandler(exporter, []string{"Brand"}, log)
+
+	recorder := httptest.NewRecorder()
+	handler.Export(recorder, httptest.NewRequest(http.MethodPost, "/export", nil))
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.Export(recorder, httptest.NewRequest(http.MethodPost, "/export", nil))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"The job queue is full. Please retry later"}`, recorder.Body.String())
+}
+
+type lostLeader struct{}
+
+func (lostLeader) IsLeader() bool {
+	return false
+}
+
+func TestRequestHandler_ExportLostLease(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	exporter := export.NewFullExporter(1, noopUpdater{}, noopInquirer{}, export.NewCsvExporter(), log)
+	//the handler still takes the replica for the leader, the exporter checking the lease again when creating the job
+	exporter.Leader = lostLeader{}
+	handler := NewRequestHandler(exporter, []string{"Brand"}, log)
+
+	recorder := httptest.NewRecorder()
+	handler.Export(recorder, httptest.NewRequest(http.MethodPost, "/export", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"Export jobs are run by the leader replica holding the lease. Please retry later"}`, recorder.Body.String())
+}
+
+type failingInquirer struct{}
+
+func (failingInquirer) Inquire(_ context.Context, candidates []string, opts concept.Options, tid string) []*concept.Worker {
+	var workers []*concept.Worker
+	for _, c := range candidates {
+		worker := &concept.Worker{ConceptType: c, RecordCh: make(chan db.Record), Errch: make(chan error, 1), Status: concept.STARTING}
+		worker.Errch <- errors.New("Neo err")
+		workers = append(workers, worker)
+	}
+	return workers
+}
+
+func TestRequestHandler_RetryChecksScopesOfConceptTypes(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	exporter := export.NewFullExporter(1, noopUpdater{}, failingInquirer{}, export.NewCsvExporter(), log)
+	handler := NewRequestHandler(exporter, []string{"Brand", "Person"}, log)
+	handler.Auth = auth.NewAuthenticator([]auth.Key{
+		{ID: "brands", Secret: "brands", Scopes: []string{auth.RetryScope, auth.ExportScope("Brand")}},
+		{ID: "ops", Secret: "ops", Scopes: []string{auth.RetryScope, auth.ExportScope("*")}},
+	})
+	router := mux.NewRouter()
+	router.HandleFunc("/jobs/{id}/retry", handler.Retry).Methods(http.MethodPost)
+	server := handler.Auth.Middleware(router)
+	parent, err := exporter.StartJob(context.Background(), []string{"Brand", "Person"}, concept.Options{}, "", "tid_1234")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return !exporter.IsRunningJob() }, time.Second, time.Millisecond)
+
+	request := httptest.NewRequest(http.MethodPost, "/jobs/"+parent.ID+"/retry", nil)
+	request.Header.Set(auth.APIKeyHeader, "brands")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Forbidden","status":403,"detail":"API key brands is not allowed the export:Person scope"}`, recorder.Body.String())
+	assert.Len(t, exporter.GetJobs(), 1)
+
+	request = httptest.NewRequest(http.MethodPost, "/jobs/"+parent.ID+"/retry", nil)
+	request.Header.Set(auth.APIKeyHeader, "ops")
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Eventually(t, func() bool { return !exporter.IsRunningJob() }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,162 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/auth"
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/Financial-Times/concept-exporter/tracing"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+)
+
+const (
+	//streamFlushRows is how many rows are written between two flushes of a streamed export, so the client gets them as they are read
+	streamFlushRows = 1000
+	//streamsBusyRetryAfter is the seconds a streamed export or a preview rejected by the concurrency limit should be retried after
+	streamsBusyRetryAfter = 30
+)
+
+//takeStream takes a token of Streams for a request reading Neo4j outside the jobs, rejecting the request once every token is taken
+func (handler *RequestHandler) takeStream(writer http.ResponseWriter) bool {
+	select {
+	case handler.Streams <- struct{}{}:
+		return true
+	default:
+		writer.Header().Set("Retry-After", strconv.Itoa(streamsBusyRetryAfter))
+		problem.Write(writer, http.StatusTooManyRequests, fmt.Sprintf("%v exports are already streamed or previewed. Please retry later", cap(handler.Streams)), nil)
+		return false
+	}
+}
+
+func (handler *RequestHandler) releaseStream() {
+	<-handler.Streams
+}
+
+type responseControllerKey struct{}
+
+//KeepResponseController keeps the controller of the response of the server in the context of the request. The writers of the
+//logging and metrics handlers don't unwrap to the response of the server, so its write deadline can't be reached through them
+func KeepResponseController(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := context.WithValue(request.Context(), responseControllerKey{}, http.NewResponseController(writer))
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+//responseControllerOf returns the controller kept by KeepResponseController, or the controller of the writer without it
+func responseControllerOf(writer http.ResponseWriter, request *http.Request) *http.ResponseController {
+	if controller, ok := request.Context().Value(responseControllerKey{}).(*http.ResponseController); ok {
+		return controller
+	}
+	return http.NewResponseController(writer)
+}
+
+//GetExportStream reads every concept of a type and streams the rows of its file straight into the response, without
+//creating a job nor uploading anything. The Streams channel bounds how many exports are streamed at the same time, and none are
+//streamed while a job is running
+func (handler *RequestHandler) GetExportStream(writer http.ResponseWriter, request *http.Request) {
+	tid := transactionidutils.GetTransactionIDFromRequest(request)
+	conceptType := mux.Vars(request)["conceptType"]
+	if !contains(handler.ConceptTypes, conceptType) {
+		problem.Write(writer, http.StatusNotFound, fmt.Sprintf("%v is not one of the supported concept types %v", conceptType, handler.ConceptTypes), nil)
+		return
+	}
+	if !handler.authorize(writer, request, auth.ExportScope(conceptType)) {
+		return
+	}
+	format := export.CSVFormat
+	if f := request.URL.Query().Get("format"); f != "" {
+		format = f
+		if format != export.CSVFormat && format != export.JSONLinesFormat {
+			problem.Write(writer, http.StatusBadRequest, "The export request is invalid",
+				[]problem.InvalidParam{{Name: "format", Reason: fmt.Sprintf("must be one of %v", []string{export.CSVFormat, export.JSONLinesFormat})}})
+			return
+		}
+	}
+
+	busy, err := handler.runsJob()
+	if err != nil {
+		handler.failLeaderJobs(writer, request, err)
+		return
+	}
+	if busy {
+		writer.Header().Set("Retry-After", strconv.Itoa(streamsBusyRetryAfter))
+		problem.Write(writer, http.StatusServiceUnavailable, "Exports aren't streamed while an export job is running, so they don't slow it down. Please retry later", nil)
+		return
+	}
+	if !handler.takeStream(writer) {
+		return
+	}
+	defer handler.releaseStream()
+	logEntry := handler.Log.WithTransactionID(tid)
+	//the server WriteTimeout would cut the streams of the large concept types, which are written for as long as they are read
+	if err := responseControllerOf(writer, request).SetWriteDeadline(time.Time{}); err != nil {
+		logEntry.WithError(err).Warnf("The %v stream may be cut by the write timeout of the server", conceptType)
+	}
+	opts := concept.Options{}
+	rows, err := export.NewStreamWriter(writer, conceptType, opts, format)
+	if err != nil {
+		problem.Write(writer, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	flusher, _ := writer.(http.Flusher)
+	started := false
+	written := 0
+	start := func() error {
+		started = true
+		fileName := handler.Exporter.Exporter.GetFileName(conceptType)
+		if format == export.JSONLinesFormat {
+			writer.Header().Set("Content-Type", "application/x-ndjson")
+			fileName = conceptType + ".jsonl"
+		} else {
+			writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		}
+		writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%v"`, fileName))
+		writer.Header().Set("X-Content-Type-Options", "nosniff")
+		writer.WriteHeader(http.StatusOK)
+		return rows.WriteHeader()
+	}
+	count, err := handler.Streamer.Stream(tracing.Extract(request), conceptType, opts, func(r db.Record) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := rows.Write(r); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%streamFlushRows == 0 {
+			if err := rows.Flush(); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+		return nil
+	}, tid)
+	if err != nil && !started {
+		logEntry.WithError(err).Errorf("Failed to read %v concepts to stream", conceptType)
+		problem.Write(writer, http.StatusServiceUnavailable, fmt.Sprintf("Failed to read %v concepts", conceptType), nil)
+		return
+	}
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = rows.Flush()
+	}
+	if err != nil {
+		//the status was sent already, so the response is aborted for the client to tell the file is truncated
+		logEntry.WithError(err).Warnf("Streaming %v concepts stopped after %v rows", conceptType, count)
+		panic(http.ErrAbortHandler)
+	}
+	logEntry.Infof("Streamed %v %v concepts", count, conceptType)
+}
@@ -0,0 +1,218 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/concept-exporter/concept"
+	"github.com/Financial-Times/concept-exporter/db"
+	"github.com/Financial-Times/concept-exporter/export"
+	"github.com/Financial-Times/concept-exporter/problem"
+	"github.com/Financial-Times/go-logger/v2"
+	"github.com/Financial-Times/http-handlers-go/v2/httphandlers"
+	"github.com/gorilla/mux"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedStreamer struct {
+	records []db.Record
+	err     error
+	//stopErr is returned once the records are written
+	stopErr error
+}
+
+func (s fixedStreamer) Stream(_ context.Context, conceptType string, opts concept.Options, write func(db.Record) error, tid string) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	for i, r := range s.records {
+		if err := write(r); err != nil {
+			return i, err
+		}
+	}
+	return len(s.records), s.stopErr
+}
+
+//slowStreamer writes its records after a pause each, for the streams written longer than the write timeout of the server
+type slowStreamer struct {
+	records []db.Record
+	pause   time.Duration
+}
+
+func (s slowStreamer) Stream(_ context.Context, conceptType string, opts concept.Options, write func(db.Record) error, tid string) (int, error) {
+	for i, r := range s.records {
+		time.Sleep(s.pause)
+		if err := write(r); err != nil {
+			return i, err
+		}
+	}
+	return len(s.records), nil
+}
+
+func TestRequestHandler_GetExportStream(t *testing.T) {
+	brand := db.Concept{Id: "http://api.ft.com/things/brand", PrefLabel: "Brand", ApiUrl: "http://api.ft.com/brands/brand"}
+	tests := []struct {
+		name        string
+		url         string
+		streamer    fixedStreamer
+		busy        bool
+		status      int
+		contentType string
+		disposition string
+		body        string
+	}{
+		{
+			name:        "CSV",
+			url:         "/export/Brand",
+			streamer:    fixedStreamer{records: []db.Record{brand}},
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			disposition: `attachment; filename="Brand.csv"`,
+			body:        "id,prefLabel,apiUrl\nhttp://api.ft.com/things/brand,Brand,http://api.ft.com/brands/brand\n",
+		},
+		{
+			name:        "JSON lines",
+			url:         "/export/Brand?format=jsonl",
+			streamer:    fixedStreamer{records: []db.Record{brand}},
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			disposition: `attachment; filename="Brand.jsonl"`,
+			body:        `{"apiUrl":"http://api.ft.com/brands/brand","id":"http://api.ft.com/things/brand","prefLabel":"Brand"}` + "\n",
+		},
+		{
+			name:        "No concepts",
+			url:         "/export/Brand",
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			disposition: `attachment; filename="Brand.csv"`,
+			body:        "id,prefLabel,apiUrl\n",
+		},
+		{
+			name:        "Read failure",
+			url:         "/export/Brand",
+			streamer:    fixedStreamer{err: errors.New("neo down")},
+			status:      http.StatusServiceUnavailable,
+			contentType: problem.ContentType,
+			body:        `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"Failed to read Brand concepts"}` + "\n",
+		},
+		{
+			name:        "Invalid format",
+			url:         "/export/Brand?format=json",
+			status:      http.StatusBadRequest,
+			contentType: problem.ContentType,
+			body:        `{"type":"about:blank","title":"Bad Request","status":400,"detail":"The export request is invalid","invalid-params":[{"name":"format","reason":"must be one of [csv jsonl]"}]}` + "\n",
+		},
+		{
+			name:        "Unsupported concept type",
+			url:         "/export/Genre",
+			status:      http.StatusNotFound,
+			contentType: problem.ContentType,
+			body:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"Genre is not one of the supported concept types [Brand Person Organisation]"}` + "\n",
+		},
+		{
+			name:        "Too many streams",
+			url:         "/export/Brand",
+			busy:        true,
+			status:      http.StatusTooManyRequests,
+			contentType: problem.ContentType,
+			body:        `{"type":"about:blank","title":"Too Many Requests","status":429,"detail":"1 exports are already streamed or previewed. Please retry later"}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := newTestRequestHandler()
+			handler.Streamer = test.streamer
+			handler.Streams = make(chan struct{}, 1)
+			if test.busy {
+				handler.Streams <- struct{}{}
+			}
+			router := mux.NewRouter()
+			router.HandleFunc("/export/{conceptType}", handler.GetExportStream).Methods(http.MethodGet)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.url, nil))
+			assert.Equal(t, test.status, recorder.Code)
+			assert.Equal(t, test.contentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, test.disposition, recorder.Header().Get("Content-Disposition"))
+			assert.Equal(t, test.body, recorder.Body.String())
+			if test.busy {
+				assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+				assert.Len(t, handler.Streams, 1)
+			} else {
+				assert.Empty(t, handler.Streams, "the stream token should be released")
+			}
+			assert.Empty(t, handler.Exporter.GetJobs())
+		})
+	}
+}
+
+func TestRequestHandler_GetExportStreamAbortedOnceStarted(t *testing.T) {
+	handler := newTestRequestHandler()
+	handler.Streamer = fixedStreamer{records: []db.Record{db.Concept{Id: "http://api.ft.com/things/brand"}}, stopErr: errors.New("Neo err")}
+	handler.Streams = make(chan struct{}, 1)
+	router := mux.NewRouter()
+	router.HandleFunc("/export/{conceptType}", handler.GetExportStream).Methods(http.MethodGet)
+
+	recorder := httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/export/Brand", nil))
+	})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, handler.Streams, "the stream token should be released")
+}
+
+func TestRequestHandler_GetExportStreamWhileJobIsRunning(t *testing.T) {
+	log := logger.NewUPPLogger("Test", "PANIC")
+	inquirer := blockingInquirer{release: make(chan struct{})}
+	defer close(inquirer.release)
+	exporter := export.NewFullExporter(1, noopUpdater{}, inquirer, export.NewCsvExporter(), log)
+	handler := NewRequestHandler(exporter, []string{"Brand"}, log)
+	handler.Streamer = fixedStreamer{}
+	handler.Streams = make(chan struct{}, 1)
+	router := mux.NewRouter()
+	router.HandleFunc("/export/{conceptType}", handler.GetExportStream).Methods(http.MethodGet)
+	_, err := exporter.QueueJob(context.Background(), []string{"Brand"}, concept.Options{}, nil, "", "tid_1234")
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/export/Brand", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, problem.ContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"Exports aren't streamed while an export job is running, so they don't slow it down. Please retry later"}`+"\n", recorder.Body.String())
+	assert.Empty(t, handler.Streams)
+}
+
+func TestRequestHandler_GetExportStreamOutlivesWriteTimeoutThroughMiddlewares(t *testing.T) {
+	handler := newTestRequestHandler()
+	handler.Streamer = slowStreamer{
+		records: []db.Record{db.Concept{Id: "http://api.ft.com/things/brand1"}, db.Concept{Id: "http://api.ft.com/things/brand2"}},
+		pause:   150 * time.Millisecond,
+	}
+	handler.Streams = make(chan struct{}, 1)
+	router := mux.NewRouter()
+	router.HandleFunc("/export/{conceptType}", handler.GetExportStream).Methods(http.MethodGet)
+	var monitoringRouter http.Handler = router
+	monitoringRouter = httphandlers.TransactionAwareRequestLoggingHandler(handler.Log, monitoringRouter)
+	monitoringRouter = httphandlers.HTTPMetricsHandler(metrics.NewRegistry(), monitoringRouter)
+	server := httptest.NewUnstartedServer(KeepResponseController(monitoringRouter))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/export/Brand")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "id,prefLabel,apiUrl\nhttp://api.ft.com/things/brand1,,\nhttp://api.ft.com/things/brand2,,\n", string(body))
+}